@@ -0,0 +1,210 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"qldiemsv/models/entity"
+	"sort"
+	"time"
+)
+
+// AcademicRecord is one graded subject of a student as used by the academic summary.
+type AcademicRecord struct {
+	Term       string
+	Credits    int
+	TotalScore float64
+	GPAScore   float64
+	Passed     bool
+	// Counted is false for the attempts of a retaken subject that the attempt policy leaves out of the cumulative GPA
+	Counted bool
+}
+
+type TermSummary struct {
+	Term             string  `json:"term"`
+	GPA10            float64 `json:"gpa_10"`
+	GPA4             float64 `json:"gpa_4"`
+	AttemptedCredits int     `json:"attempted_credits"`
+	EarnedCredits    int     `json:"earned_credits"`
+}
+
+type AcademicSummary struct {
+	Terms            []TermSummary `json:"terms"`
+	CumulativeGPA10  float64       `json:"cumulative_gpa_10"`
+	CumulativeGPA4   float64       `json:"cumulative_gpa_4"`
+	AttemptedCredits int           `json:"attempted_credits"`
+	EarnedCredits    int           `json:"earned_credits"`
+	Classification   string        `json:"classification"`
+	AcademicWarning  bool          `json:"academic_warning"`
+}
+
+const (
+	AttemptPolicyLatest  = "latest"
+	AttemptPolicyHighest = "highest"
+)
+
+const (
+	// A term GPA below this on the 4-point scale puts the student under academic warning
+	termWarningGPA4 = 1.0
+	// A cumulative GPA below this on the 4-point scale puts the student under academic warning
+	cumulativeWarningGPA4 = 2.0
+)
+
+// GradeTerm derives the term of a grade recorded before academic terms existed from its date following the
+// academic calendar, where the first semester starts in August, the second in January and the summer term in June.
+func GradeTerm(t time.Time) string {
+	year := t.Year()
+	switch month := t.Month(); {
+	case month >= time.August:
+		return fmt.Sprintf("%d-%d/1", year, year+1)
+	case month >= time.June:
+		return fmt.Sprintf("%d-%d/3", year-1, year)
+	default:
+		return fmt.Sprintf("%d-%d/2", year-1, year)
+	}
+}
+
+// AttemptPolicy is which attempt of a retaken subject counts toward the cumulative GPA, read from GRADE_ATTEMPT_POLICY.
+func AttemptPolicy() string {
+	if GetEnvString("GRADE_ATTEMPT_POLICY", AttemptPolicyHighest) == AttemptPolicyLatest {
+		return AttemptPolicyLatest
+	}
+	return AttemptPolicyHighest
+}
+
+func isLaterAttempt(a entity.Grade, b entity.Grade) bool {
+	if a.Attempt != b.Attempt {
+		return a.Attempt > b.Attempt
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID > b.ID
+}
+
+// CountedAttempts picks the attempt of each subject that counts toward the cumulative GPA and returns the picked
+// grade ids. The grades must all belong to one student and have their results computed, a tie on the highest
+// score goes to the later attempt.
+func CountedAttempts(grades []entity.Grade, policy string) map[uint]bool {
+	picked := make(map[string]entity.Grade)
+	for _, grade := range grades {
+		current, ok := picked[grade.SubjectID]
+		switch {
+		case !ok:
+		case policy == AttemptPolicyLatest:
+			if !isLaterAttempt(grade, current) {
+				continue
+			}
+		case grade.TotalScore < current.TotalScore:
+			continue
+		case grade.TotalScore == current.TotalScore && !isLaterAttempt(grade, current):
+			continue
+		}
+		picked[grade.SubjectID] = grade
+	}
+
+	counted := make(map[uint]bool, len(picked))
+	for _, grade := range picked {
+		counted[grade.ID] = true
+	}
+
+	return counted
+}
+
+func roundGPA(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// Classification is the standing label of a cumulative GPA on the 4-point scale.
+func Classification(gpa4 float64) string {
+	switch {
+	case gpa4 >= 3.6:
+		return "Xuất sắc"
+	case gpa4 >= 3.2:
+		return "Giỏi"
+	case gpa4 >= 2.5:
+		return "Khá"
+	case gpa4 >= 2.0:
+		return "Trung bình"
+	case gpa4 >= 1.0:
+		return "Yếu"
+	default:
+		return "Kém"
+	}
+}
+
+// SummarizeAcademic computes the term and cumulative GPA of the records, weighted by credits. A term GPA covers
+// every attempted subject, the cumulative GPA only the passed ones, as the credits earned, of the counted attempts.
+func SummarizeAcademic(records []AcademicRecord) AcademicSummary {
+	type totals struct {
+		attempted, earned int
+		score10, score4   float64
+		earned10, earned4 float64
+	}
+
+	byTerm := make(map[string]*totals)
+	cumulative := totals{}
+	for _, record := range records {
+		term, ok := byTerm[record.Term]
+		if !ok {
+			term = &totals{}
+			byTerm[record.Term] = term
+		}
+
+		credits := float64(record.Credits)
+		term.attempted += record.Credits
+		term.score10 += record.TotalScore * credits
+		term.score4 += record.GPAScore * credits
+
+		if record.Passed {
+			term.earned += record.Credits
+		}
+
+		if !record.Counted {
+			continue
+		}
+
+		cumulative.attempted += record.Credits
+		if record.Passed {
+			cumulative.earned += record.Credits
+			cumulative.earned10 += record.TotalScore * credits
+			cumulative.earned4 += record.GPAScore * credits
+		}
+	}
+
+	summary := AcademicSummary{
+		Terms:            make([]TermSummary, 0, len(byTerm)),
+		AttemptedCredits: cumulative.attempted,
+		EarnedCredits:    cumulative.earned,
+	}
+
+	for name, term := range byTerm {
+		termSummary := TermSummary{
+			Term:             name,
+			AttemptedCredits: term.attempted,
+			EarnedCredits:    term.earned,
+		}
+		if term.attempted > 0 {
+			termSummary.GPA10 = roundGPA(term.score10 / float64(term.attempted))
+			termSummary.GPA4 = roundGPA(term.score4 / float64(term.attempted))
+		}
+		summary.Terms = append(summary.Terms, termSummary)
+	}
+
+	sort.Slice(summary.Terms, func(i, j int) bool {
+		return summary.Terms[i].Term < summary.Terms[j].Term
+	})
+
+	if cumulative.earned > 0 {
+		summary.CumulativeGPA10 = roundGPA(cumulative.earned10 / float64(cumulative.earned))
+		summary.CumulativeGPA4 = roundGPA(cumulative.earned4 / float64(cumulative.earned))
+	}
+
+	if len(summary.Terms) > 0 {
+		summary.Classification = Classification(summary.CumulativeGPA4)
+
+		lastTerm := summary.Terms[len(summary.Terms)-1]
+		summary.AcademicWarning = lastTerm.GPA4 < termWarningGPA4 || summary.CumulativeGPA4 < cumulativeWarningGPA4
+	}
+
+	return summary
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"log"
+	"os"
+	"qldiemsv/models/entity"
+)
+
+// BootstrapAdmin gives the deployment its first admin while it has none, so the admin routes can be reached with
+// self-registration turned off. The account of INITIAL_ADMIN_EMAIL is promoted, or created with
+// INITIAL_ADMIN_USERNAME and INITIAL_ADMIN_PASSWORD when it does not exist yet. Nothing changes once an admin exists.
+func BootstrapAdmin() {
+	email := os.Getenv("INITIAL_ADMIN_EMAIL")
+	if email == "" {
+		return
+	}
+
+	var admins int64
+	if err := DBConn.Model(&entity.User{}).Where("role = ?", entity.RoleAdmin).Count(&admins).Error; err != nil {
+		log.Println("Error checking the admin accounts:", err)
+		return
+	}
+	if admins > 0 {
+		return
+	}
+
+	var user entity.User
+	err := DBConn.First(&user, "email = ?", email).Error
+	if err == nil {
+		if err := DBConn.Model(&user).Update("role", entity.RoleAdmin).Error; err != nil {
+			log.Println("Error promoting the initial admin:", err)
+			return
+		}
+		log.Println("Promoted " + email + " to admin")
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Println("Error loading the initial admin:", err)
+		return
+	}
+
+	username := os.Getenv("INITIAL_ADMIN_USERNAME")
+	password := os.Getenv("INITIAL_ADMIN_PASSWORD")
+	if username == "" || password == "" {
+		log.Println("No account with " + email + ", set INITIAL_ADMIN_USERNAME and INITIAL_ADMIN_PASSWORD to create it")
+		return
+	}
+	if err := ValidatePassword(password); err != nil {
+		log.Println("Invalid INITIAL_ADMIN_PASSWORD:", err)
+		return
+	}
+
+	hashPassword, err := bcrypt.GenerateFromPassword([]byte(password), 11)
+	if err != nil {
+		log.Println("Error creating the initial admin:", err)
+		return
+	}
+
+	admin := entity.User{
+		FirstName: "Admin",
+		LastName:  "Admin",
+		UserName:  username,
+		Email:     &email,
+		Password:  string(hashPassword),
+		Role:      entity.RoleAdmin,
+	}
+	if err := DBConn.Create(&admin).Error; err != nil {
+		log.Println("Error creating the initial admin:", err)
+		return
+	}
+	log.Println("Created the initial admin " + username)
+}
@@ -0,0 +1,137 @@
+package common
+
+import (
+	"fmt"
+	"qldiemsv/models/entity"
+	"strings"
+)
+
+var legacyComponentNames = map[string]string{
+	entity.ComponentCodeProcess: "Điểm quá trình",
+	entity.ComponentCodeMidterm: "Điểm giữa kỳ",
+	entity.ComponentCodeFinal:   "Điểm cuối kỳ",
+}
+
+// LegacyComponents are the process, midterm and final components of a subject with the given percentages.
+func LegacyComponents(subjectId string, processPercentage int8, midtermPercentage int8, finalPercentage int8) []entity.AssessmentComponent {
+	return []entity.AssessmentComponent{
+		{SubjectID: subjectId, Code: entity.ComponentCodeProcess, Name: legacyComponentNames[entity.ComponentCodeProcess], Percentage: processPercentage, Position: 0},
+		{SubjectID: subjectId, Code: entity.ComponentCodeMidterm, Name: legacyComponentNames[entity.ComponentCodeMidterm], Percentage: midtermPercentage, Position: 1},
+		{SubjectID: subjectId, Code: entity.ComponentCodeFinal, Name: legacyComponentNames[entity.ComponentCodeFinal], Percentage: finalPercentage, Position: 2},
+	}
+}
+
+// SubjectComponents returns the assessment components of the subject. Subjects created before components were
+// configurable have none stored and keep grading on their process, midterm and final percentages.
+func SubjectComponents(subject entity.Subject) []entity.AssessmentComponent {
+	if len(subject.Components) > 0 {
+		return subject.Components
+	}
+	return LegacyComponents(subject.ID, subject.ProcessPercentage, subject.MidtermPercentage, subject.FinalPercentage)
+}
+
+// LegacyComponentScores are the component scores given as process, midterm and final scores.
+func LegacyComponentScores(processScore float64, midtermScore float64, finalScore float64, processStatus string, midtermStatus string, finalStatus string) []entity.GradeComponentScore {
+	return []entity.GradeComponentScore{
+		{Code: entity.ComponentCodeProcess, Score: processScore, Status: processStatus},
+		{Code: entity.ComponentCodeMidterm, Score: midtermScore, Status: midtermStatus},
+		{Code: entity.ComponentCodeFinal, Score: finalScore, Status: finalStatus},
+	}
+}
+
+// GradeComponentScores returns the component scores of the grade, grades recorded before components were
+// configurable only have the process, midterm and final columns.
+func GradeComponentScores(grade entity.Grade) []entity.GradeComponentScore {
+	if len(grade.Components) > 0 {
+		return grade.Components
+	}
+	return LegacyComponentScores(grade.ProcessScore, grade.MidtermScore, grade.FinalScore, grade.ProcessStatus, grade.MidtermStatus, grade.FinalStatus)
+}
+
+// SetGradeComponents replaces the component scores of the grade. A component with a status has no score, the
+// status decides instead, and the process, midterm and final components are mirrored into their columns.
+func SetGradeComponents(grade *entity.Grade, scores []entity.GradeComponentScore) {
+	grade.Components = make([]entity.GradeComponentScore, 0, len(scores))
+	grade.ProcessScore, grade.MidtermScore, grade.FinalScore = 0, 0, 0
+	grade.ProcessStatus, grade.MidtermStatus, grade.FinalStatus = "", "", ""
+
+	for _, score := range scores {
+		if score.Status != "" {
+			score.Score = 0
+		}
+		score.ID = 0
+		score.GradeID = grade.ID
+		grade.Components = append(grade.Components, score)
+
+		switch score.Code {
+		case entity.ComponentCodeProcess:
+			grade.ProcessScore, grade.ProcessStatus = score.Score, score.Status
+		case entity.ComponentCodeMidterm:
+			grade.MidtermScore, grade.MidtermStatus = score.Score, score.Status
+		case entity.ComponentCodeFinal:
+			grade.FinalScore, grade.FinalStatus = score.Score, score.Status
+		}
+	}
+}
+
+// CheckComponentPercentages reports why the components cannot make up a subject, or an empty string if they can.
+func CheckComponentPercentages(components []entity.AssessmentComponent) string {
+	if len(components) == 0 {
+		return "Môn học phải có ít nhất một thành phần điểm"
+	}
+
+	codes := make(map[string]bool, len(components))
+	total := 0
+	for _, component := range components {
+		if codes[component.Code] {
+			return "Mã thành phần điểm " + component.Code + " bị trùng"
+		}
+		codes[component.Code] = true
+		total += int(component.Percentage)
+	}
+
+	if total != 100 {
+		return "Tổng % phải bằng 100"
+	}
+
+	return ""
+}
+
+// ComponentsText lists the component scores of a grade for the Excel exports, such as "Điểm quá trình: 8.00; Điểm cuối kỳ: Vắng có phép".
+func ComponentsText(grade entity.Grade, subject entity.Subject) string {
+	scores := make(map[string]entity.GradeComponentScore)
+	for _, score := range GradeComponentScores(grade) {
+		scores[score.Code] = score
+	}
+
+	parts := make([]string, 0)
+	for _, component := range SubjectComponents(subject) {
+		score := scores[component.Code]
+		parts = append(parts, fmt.Sprintf("%s: %s", component.Name, ComponentText(score.Score, score.Status)))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// SetSubjectComponents replaces the components of the subject and mirrors the percentages of the process, midterm
+// and final components into their columns.
+func SetSubjectComponents(subject *entity.Subject, components []entity.AssessmentComponent) {
+	subject.Components = make([]entity.AssessmentComponent, 0, len(components))
+	subject.ProcessPercentage, subject.MidtermPercentage, subject.FinalPercentage = 0, 0, 0
+
+	for i, component := range components {
+		component.ID = 0
+		component.SubjectID = subject.ID
+		component.Position = i
+		subject.Components = append(subject.Components, component)
+
+		switch component.Code {
+		case entity.ComponentCodeProcess:
+			subject.ProcessPercentage = component.Percentage
+		case entity.ComponentCodeMidterm:
+			subject.MidtermPercentage = component.Percentage
+		case entity.ComponentCodeFinal:
+			subject.FinalPercentage = component.Percentage
+		}
+	}
+}
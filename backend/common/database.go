@@ -33,10 +33,10 @@ func ConnectDB() {
 func runMigrate() {
 	if os.Getenv("APP_ENV") == "development" {
 		//Drop table
-		//if err := DBConn.Migrator().DropTable(&entity.Department{}, &entity.Instructor{}, &entity.Subject{}, &entity.Student{}, &entity.Grade{}, &entity.Class{}, &entity.InstructorAssignment{}, &entity.StudentRegistration{}, &entity.User{}); err != nil {
+		//if err := DBConn.Migrator().DropTable(&entity.Department{}, &entity.Instructor{}, &entity.Subject{}, &entity.Student{}, &entity.Grade{}, &entity.Class{}, &entity.InstructorAssignment{}, &entity.StudentRegistration{}, &entity.User{}, &entity.UserSession{}, &entity.LoginThrottle{}, &entity.RecoveryCode{}, &entity.PasswordResetToken{}, &entity.Invitation{}, &entity.OIDCLoginState{}, &entity.APIKey{}, &entity.GradingScale{}, &entity.GradingScaleBand{}, &entity.AcademicTerm{}, &entity.GradeSheet{}, &entity.GradeAmendment{}, &entity.GradeHistory{}, &entity.AssessmentComponent{}, &entity.GradeComponentScore{}, &entity.ExportJob{}, &entity.RevokedToken{}); err != nil {
 		//	panic(err)
 		//}
-		//if err := DBConn.AutoMigrate(&entity.Department{}, &entity.Instructor{}, &entity.Subject{}, &entity.Student{}, &entity.Grade{}, &entity.Class{}, &entity.InstructorAssignment{}, &entity.StudentRegistration{}, &entity.User{}); err != nil {
+		//if err := DBConn.AutoMigrate(&entity.Department{}, &entity.Instructor{}, &entity.Subject{}, &entity.Student{}, &entity.Grade{}, &entity.Class{}, &entity.InstructorAssignment{}, &entity.StudentRegistration{}, &entity.User{}, &entity.UserSession{}, &entity.LoginThrottle{}, &entity.RecoveryCode{}, &entity.PasswordResetToken{}, &entity.Invitation{}, &entity.OIDCLoginState{}, &entity.APIKey{}, &entity.GradingScale{}, &entity.GradingScaleBand{}, &entity.AcademicTerm{}, &entity.GradeSheet{}, &entity.GradeAmendment{}, &entity.GradeHistory{}, &entity.AssessmentComponent{}, &entity.GradeComponentScore{}, &entity.ExportJob{}, &entity.RevokedToken{}); err != nil {
 		//	panic(err)
 		//}
 		log.Println("Success to migrate")
@@ -3,6 +3,9 @@ package common
 import (
 	"github.com/joho/godotenv"
 	"log"
+	"os"
+	"strconv"
+	"time"
 )
 
 func LoadEnvVar() {
@@ -11,3 +14,38 @@ func LoadEnvVar() {
 		log.Println("Error loading .env file")
 	}
 }
+
+// GetEnvDuration parses a duration such as "15m" from the environment, falling back when it is missing or invalid.
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvInt parses a positive integer from the environment, falling back when it is missing or invalid.
+func GetEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvBool parses a boolean such as "true" from the environment, falling back when it is missing or invalid.
+func GetEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvString reads a variable from the environment, falling back when it is empty.
+func GetEnvString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
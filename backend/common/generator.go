@@ -2,6 +2,9 @@ package common
 
 import (
 	"bytes"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"math/rand"
 	"strconv"
 )
@@ -17,3 +20,18 @@ func GenerateRandNum(length int) string {
 	}
 	return buf.String()
 }
+
+// GenerateRandToken returns a hex encoded, cryptographically secure random string of size bytes.
+func GenerateRandToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := crand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// HashToken returns the sha256 hex digest stored in place of a secret token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,237 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"qldiemsv/models/entity"
+	"sort"
+	"strings"
+)
+
+// DefaultGradingScale follows the credit-based 10-point to 4-point conversion. It applies when neither the
+// subject nor its department has a grading scale.
+var DefaultGradingScale = entity.GradingScale{
+	Name:         "Mặc định",
+	RoundingStep: 0.1,
+	Bands: []entity.GradingScaleBand{
+		{Letter: "A+", MinScore: 9.0, Point: 4.0, Passed: true},
+		{Letter: "A", MinScore: 8.5, Point: 3.7, Passed: true},
+		{Letter: "B+", MinScore: 8.0, Point: 3.5, Passed: true},
+		{Letter: "B", MinScore: 7.0, Point: 3.0, Passed: true},
+		{Letter: "C+", MinScore: 6.5, Point: 2.5, Passed: true},
+		{Letter: "C", MinScore: 5.5, Point: 2.0, Passed: true},
+		{Letter: "D+", MinScore: 5.0, Point: 1.5, Passed: true},
+		{Letter: "D", MinScore: 4.0, Point: 1.0, Passed: true},
+		{Letter: "F", MinScore: 0, Point: 0, Passed: false},
+	},
+}
+
+// RoundScore rounds half up to the nearest multiple of step, the epsilon absorbs float errors such as 6.449999.
+func RoundScore(score float64, step float64) float64 {
+	if step <= 0 {
+		return score
+	}
+	rounded := math.Floor(score/step+0.5+1e-9) * step
+	return math.Round(rounded*100) / 100
+}
+
+// Status codes of a grade component, an empty status means the component is scored normally.
+const (
+	ComponentAbsentExcused   = "absent_excused"
+	ComponentAbsentUnexcused = "absent_unexcused"
+	ComponentExempt          = "exempt"
+	ComponentIncomplete      = "incomplete"
+	ComponentBanned          = "banned"
+)
+
+// Result statuses of a whole grade, an empty status means the grade is graded on its total.
+const (
+	ResultIncomplete = "incomplete"
+	ResultExempt     = "exempt"
+	ResultBanned     = "banned"
+)
+
+const (
+	LetterIncomplete = "I"
+	LetterExempt     = "M"
+)
+
+var componentStatusTexts = map[string]string{
+	ComponentAbsentExcused:   "Vắng có phép",
+	ComponentAbsentUnexcused: "Vắng không phép",
+	ComponentExempt:          "Miễn",
+	ComponentIncomplete:      "Chưa hoàn thành",
+	ComponentBanned:          "Cấm thi",
+}
+
+// ComponentStatusText is the label of a component status shown in the exports and the transcript.
+func ComponentStatusText(status string) string {
+	return componentStatusTexts[status]
+}
+
+// ParseComponentStatus reads a component status written as its code or its label, as in an imported grade sheet.
+func ParseComponentStatus(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	for status, label := range componentStatusTexts {
+		if strings.EqualFold(text, status) || strings.EqualFold(text, label) {
+			return status, true
+		}
+	}
+	return "", false
+}
+
+type gradeComponent struct {
+	score      float64
+	percentage float64
+	status     string
+}
+
+// gradeComponents pairs the components of the subject with the scores of the grade, a component without a
+// score yet counts as 0.
+func gradeComponents(grade entity.Grade, subject entity.Subject) []gradeComponent {
+	scores := make(map[string]entity.GradeComponentScore)
+	for _, score := range GradeComponentScores(grade) {
+		scores[score.Code] = score
+	}
+
+	components := SubjectComponents(subject)
+	result := make([]gradeComponent, 0, len(components))
+	for _, component := range components {
+		score := scores[component.Code]
+		result = append(result, gradeComponent{score.Score, float64(component.Percentage), score.Status})
+	}
+
+	return result
+}
+
+// WeightedScore combines the grade components with the percentages of the subject on the 10-point scale and
+// returns the result status the component statuses lead to:
+//   - absent without excuse scores the component 0
+//   - banned scores the component 0 and fails the subject whatever the total
+//   - exempt leaves the component out and scales the other percentages up to 100%, a grade exempt in every
+//     component is exempt from the subject
+//   - absent with excuse and incomplete leave the grade incomplete until the component is graded
+//
+// A ban outweighs an incomplete component, which outweighs exemptions.
+func WeightedScore(grade entity.Grade, subject entity.Subject) (float64, string) {
+	var weighted, percentages float64
+	banned, incomplete, exempt := false, false, false
+
+	for _, component := range gradeComponents(grade, subject) {
+		switch component.status {
+		case ComponentExempt:
+			exempt = true
+			continue
+		case ComponentAbsentExcused, ComponentIncomplete:
+			incomplete = true
+		case ComponentBanned:
+			banned = true
+			component.score = 0
+		case ComponentAbsentUnexcused:
+			component.score = 0
+		}
+		weighted += component.score * component.percentage
+		percentages += component.percentage
+	}
+
+	switch {
+	case banned:
+		return weighted / 100, ResultBanned
+	case incomplete:
+		return 0, ResultIncomplete
+	case exempt && percentages == 0:
+		return 0, ResultExempt
+	case exempt:
+		return weighted / percentages, ""
+	}
+
+	return weighted / 100, ""
+}
+
+// SortGradingScaleBands orders the bands from the highest minimum score, as FindGradeBand expects.
+func SortGradingScaleBands(bands []entity.GradingScaleBand) {
+	sort.SliceStable(bands, func(i, j int) bool {
+		return bands[i].MinScore > bands[j].MinScore
+	})
+}
+
+// FindGradeBand returns the highest band the score reaches, bands must be ordered from the highest.
+func FindGradeBand(bands []entity.GradingScaleBand, score float64) entity.GradingScaleBand {
+	for _, band := range bands {
+		if score >= band.MinScore {
+			return band
+		}
+	}
+	if len(bands) == 0 {
+		return entity.GradingScaleBand{}
+	}
+	return bands[len(bands)-1]
+}
+
+// ApplyGradeResult fills the computed total, 4-point score, letter grade, pass flag and result status of the grade.
+// A locked grade keeps the results stored when its sheet was submitted.
+func ApplyGradeResult(grade *entity.Grade, subject entity.Subject, scale entity.GradingScale) {
+	if grade.ResultLockedAt != nil {
+		return
+	}
+
+	weighted, status := WeightedScore(*grade, subject)
+	total := RoundScore(weighted, scale.RoundingStep)
+	band := FindGradeBand(scale.Bands, total)
+
+	grade.TotalScore = total
+	grade.GPAScore = band.Point
+	grade.LetterGrade = band.Letter
+	grade.Passed = band.Passed
+	grade.ResultStatus = status
+
+	switch status {
+	case ResultBanned:
+		lowest := FindGradeBand(scale.Bands, 0)
+		grade.GPAScore = lowest.Point
+		grade.LetterGrade = lowest.Letter
+		grade.Passed = false
+	case ResultIncomplete:
+		grade.GPAScore = 0
+		grade.LetterGrade = LetterIncomplete
+		grade.Passed = false
+	case ResultExempt:
+		grade.GPAScore = 0
+		grade.LetterGrade = LetterExempt
+		grade.Passed = true
+	}
+}
+
+// CountsTowardGPA is false for incomplete and exempt grades, they have no total to average.
+func CountsTowardGPA(grade entity.Grade) bool {
+	return grade.ResultStatus != ResultIncomplete && grade.ResultStatus != ResultExempt
+}
+
+// PassedText is the result shown in the Excel exports.
+func PassedText(passed bool) string {
+	if passed {
+		return "Đạt"
+	}
+	return "Không đạt"
+}
+
+// GradeResultText is the result of a grade shown in the Excel exports and the transcript.
+func GradeResultText(grade entity.Grade) string {
+	switch grade.ResultStatus {
+	case ResultBanned:
+		return "Cấm thi"
+	case ResultIncomplete:
+		return "Chưa hoàn thành"
+	case ResultExempt:
+		return "Miễn"
+	}
+	return PassedText(grade.Passed)
+}
+
+// ComponentText is a component as shown in the Excel exports, its status if it has one or else its score.
+func ComponentText(score float64, status string) string {
+	if status != "" {
+		return ComponentStatusText(status)
+	}
+	return fmt.Sprintf("%.2f", score)
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MailSender delivers the emails of the application, pick one with MAIL_DRIVER.
+type MailSender interface {
+	Send(to string, subject string, body string) error
+}
+
+var Mailer MailSender
+
+func SetupMailer() {
+	switch os.Getenv("MAIL_DRIVER") {
+	case "smtp":
+		Mailer = SMTPMailSender{
+			Host:     os.Getenv("MAIL_SMTP_HOST"),
+			Port:     os.Getenv("MAIL_SMTP_PORT"),
+			Username: os.Getenv("MAIL_SMTP_USERNAME"),
+			Password: os.Getenv("MAIL_SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+	case "file":
+		Mailer = FileMailSender{Dir: os.Getenv("MAIL_FILE_DIR")}
+	default:
+		Mailer = LogMailSender{}
+	}
+}
+
+// LogMailSender prints the emails to the log, for local development.
+type LogMailSender struct{}
+
+func (LogMailSender) Send(to string, subject string, body string) error {
+	log.Printf("Mail to %s\nSubject: %s\n\n%s\n", to, subject, body)
+	return nil
+}
+
+// FileMailSender writes every email to its own file in Dir.
+type FileMailSender struct {
+	Dir string
+}
+
+func (s FileMailSender) Send(to string, subject string, body string) error {
+	dir := s.Dir
+	if dir == "" {
+		dir = "static/mails"
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(to))
+	content := fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s\r\n", to, subject, body)
+
+	return os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+}
+
+type SMTPMailSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s SMTPMailSender) Send(to string, subject string, body string) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n", s.From, to, subject, body)
+
+	return smtp.SendMail(s.Host+":"+s.Port, auth, s.From, []string{to}, []byte(msg))
+}
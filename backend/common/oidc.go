@@ -0,0 +1,230 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/MicahParks/keyfunc/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCConfig is read from the OIDC_* variables, the claim names map the identity provider's claims onto entity.User.
+type OIDCConfig struct {
+	Issuer         string
+	ClientID       string
+	ClientSecret   string
+	RedirectURL    string
+	Scopes         string
+	EmailClaim     string
+	UserNameClaim  string
+	FirstNameClaim string
+	LastNameClaim  string
+}
+
+type OIDCClaims struct {
+	Email     string
+	UserName  string
+	FirstName string
+	LastName  string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type OIDCProvider struct {
+	Config    OIDCConfig
+	discovery oidcDiscovery
+	jwks      *keyfunc.JWKS
+}
+
+var (
+	oidcProvider *OIDCProvider
+	oidcMutex    sync.Mutex
+	oidcClient   = &http.Client{Timeout: 10 * time.Second}
+)
+
+func OIDCEnabled() bool {
+	return GetEnvBool("OIDC_ENABLED", false) && os.Getenv("OIDC_ISSUER") != ""
+}
+
+// GetOIDCProvider discovers the identity provider on first use, so the API still starts while it is unreachable.
+func GetOIDCProvider() (*OIDCProvider, error) {
+	oidcMutex.Lock()
+	defer oidcMutex.Unlock()
+
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+
+	config := OIDCConfig{
+		Issuer:         strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/"),
+		ClientID:       os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:   os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:    os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:         GetEnvString("OIDC_SCOPES", "openid email profile"),
+		EmailClaim:     GetEnvString("OIDC_EMAIL_CLAIM", "email"),
+		UserNameClaim:  GetEnvString("OIDC_USERNAME_CLAIM", "preferred_username"),
+		FirstNameClaim: GetEnvString("OIDC_FIRST_NAME_CLAIM", "given_name"),
+		LastNameClaim:  GetEnvString("OIDC_LAST_NAME_CLAIM", "family_name"),
+	}
+
+	resp, err := oidcClient.Get(config.Issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc discovery returned status %d", resp.StatusCode)
+	}
+
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != config.Issuer {
+		return nil, fmt.Errorf("oidc issuer mismatch: %s", discovery.Issuer)
+	}
+
+	jwks, err := keyfunc.Get(discovery.JWKSURI, keyfunc.Options{
+		Client:            oidcClient,
+		RefreshUnknownKID: true,
+		RefreshRateLimit:  5 * time.Minute,
+		RefreshTimeout:    10 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	oidcProvider = &OIDCProvider{Config: config, discovery: discovery, jwks: jwks}
+
+	return oidcProvider, nil
+}
+
+// PKCEChallenge returns the S256 code challenge of a code verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p *OIDCProvider) AuthCodeURL(state string, nonce string, codeVerifier string) string {
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.Config.ClientID},
+		"redirect_uri":          {p.Config.RedirectURL},
+		"scope":                 {p.Config.Scopes},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {PKCEChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(p.discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return p.discovery.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// Exchange trades the authorization code for the raw ID token.
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.Config.RedirectURL},
+		"client_id":     {p.Config.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if p.Config.ClientSecret != "" {
+		form.Set("client_secret", p.Config.ClientSecret)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	resp, err := oidcClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var tokenResponse struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc token endpoint returned %d: %s %s", resp.StatusCode, tokenResponse.Error, tokenResponse.ErrorDescription)
+	}
+
+	if tokenResponse.IDToken == "" {
+		return "", errors.New("oidc token response has no id_token")
+	}
+
+	return tokenResponse.IDToken, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of the ID token and maps its claims.
+func (p *OIDCProvider) VerifyIDToken(rawIDToken string, nonce string) (*OIDCClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, p.jwks.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "EdDSA"}),
+		jwt.WithIssuer(p.discovery.Issuer),
+		jwt.WithAudience(p.Config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, errors.New("oidc nonce mismatch")
+	}
+
+	// An email the identity provider did not verify must not be used to match an existing account
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		return nil, errors.New("oidc email is not verified")
+	}
+
+	stringClaim := func(name string) string {
+		value, _ := claims[name].(string)
+		return strings.TrimSpace(value)
+	}
+
+	result := &OIDCClaims{
+		Email:     strings.ToLower(stringClaim(p.Config.EmailClaim)),
+		UserName:  stringClaim(p.Config.UserNameClaim),
+		FirstName: stringClaim(p.Config.FirstNameClaim),
+		LastName:  stringClaim(p.Config.LastNameClaim),
+	}
+
+	if result.Email == "" {
+		return nil, fmt.Errorf("oidc claim %s is missing", p.Config.EmailClaim)
+	}
+
+	return result, nil
+}
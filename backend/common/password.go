@@ -0,0 +1,45 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// ValidatePassword enforces the password policy configured through the PASSWORD_* variables.
+func ValidatePassword(password string) error {
+	minLength := GetEnvInt("PASSWORD_MIN_LENGTH", 8)
+
+	if len([]rune(password)) < minLength {
+		return errors.New(fmt.Sprintf("Mật khẩu phải có ít nhất %v ký tự", minLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if GetEnvBool("PASSWORD_REQUIRE_UPPER", true) && !hasUpper {
+		return errors.New("Mật khẩu phải chứa ít nhất một chữ in hoa")
+	}
+	if GetEnvBool("PASSWORD_REQUIRE_LOWER", true) && !hasLower {
+		return errors.New("Mật khẩu phải chứa ít nhất một chữ thường")
+	}
+	if GetEnvBool("PASSWORD_REQUIRE_DIGIT", true) && !hasDigit {
+		return errors.New("Mật khẩu phải chứa ít nhất một chữ số")
+	}
+	if GetEnvBool("PASSWORD_REQUIRE_SYMBOL", false) && !hasSymbol {
+		return errors.New("Mật khẩu phải chứa ít nhất một ký tự đặc biệt")
+	}
+
+	return nil
+}
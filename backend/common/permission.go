@@ -0,0 +1,110 @@
+package common
+
+import "qldiemsv/models/entity"
+
+const (
+	PermDepartmentRead    = "departments:read"
+	PermDepartmentWrite   = "departments:write"
+	PermSubjectRead       = "subjects:read"
+	PermSubjectWrite      = "subjects:write"
+	PermClassRead         = "classes:read"
+	PermClassWrite        = "classes:write"
+	PermInstructorRead    = "instructors:read"
+	PermInstructorWrite   = "instructors:write"
+	PermStudentRead       = "students:read"
+	PermStudentWrite      = "students:write"
+	PermGradeRead         = "grades:read"
+	PermGradeWrite        = "grades:write"
+	PermGradeExport       = "grades:export"
+	PermGradeApprove      = "grades:approve"
+	PermAssignmentRead    = "assignments:read"
+	PermAssignmentWrite   = "assignments:write"
+	PermRegistrationRead  = "registrations:read"
+	PermRegistrationWrite = "registrations:write"
+	PermGradingScaleRead  = "grading_scales:read"
+	PermGradingScaleWrite = "grading_scales:write"
+	PermTermRead          = "terms:read"
+	PermTermWrite         = "terms:write"
+	PermUserManage        = "users:manage"
+	PermAPIKeyManage      = "api_keys:manage"
+	PermInstructorPortal  = "portal:instructor"
+	PermStudentPortal     = "portal:student"
+	// PermBulkDelete guards the "delete all" and "delete by list" routes
+	PermBulkDelete = "bulk:delete"
+)
+
+var rolePermissions = map[string][]string{
+	entity.RoleAdmin: {
+		PermDepartmentRead, PermDepartmentWrite,
+		PermSubjectRead, PermSubjectWrite,
+		PermClassRead, PermClassWrite,
+		PermInstructorRead, PermInstructorWrite,
+		PermStudentRead, PermStudentWrite,
+		PermGradeRead, PermGradeWrite, PermGradeExport, PermGradeApprove,
+		PermAssignmentRead, PermAssignmentWrite,
+		PermRegistrationRead, PermRegistrationWrite,
+		PermGradingScaleRead, PermGradingScaleWrite,
+		PermTermRead, PermTermWrite,
+		PermUserManage,
+		PermAPIKeyManage,
+		PermBulkDelete,
+	},
+	entity.RoleDepartmentManager: {
+		PermDepartmentRead,
+		PermSubjectRead, PermSubjectWrite,
+		PermClassRead, PermClassWrite,
+		PermInstructorRead, PermInstructorWrite,
+		PermStudentRead, PermStudentWrite,
+		PermGradeRead, PermGradeWrite, PermGradeExport, PermGradeApprove,
+		PermAssignmentRead, PermAssignmentWrite,
+		PermRegistrationRead, PermRegistrationWrite,
+		PermGradingScaleRead,
+		PermTermRead,
+	},
+	entity.RoleInstructor: {
+		PermDepartmentRead,
+		PermSubjectRead,
+		PermClassRead,
+		PermInstructorRead,
+		PermStudentRead,
+		PermGradeRead, PermGradeWrite, PermGradeExport,
+		PermAssignmentRead,
+		PermRegistrationRead,
+		PermGradingScaleRead,
+		PermTermRead,
+		PermInstructorPortal,
+	},
+	// Students only reach their own data through /api/me/student, the generic read
+	// routes preload grades and student lists of other students
+	entity.RoleStudent: {
+		PermStudentPortal,
+	},
+}
+
+func RolePermissions(role string) []string {
+	return rolePermissions[role]
+}
+
+func HasPermission(granted []string, perm string) bool {
+	for _, p := range granted {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// IsAPIKeyPermission reports whether an API key may be granted the permission. Keys never manage accounts or
+// other keys, approvals need a person to answer for them, and the portals need a linked user.
+func IsAPIKeyPermission(perm string) bool {
+	switch perm {
+	case PermUserManage, PermAPIKeyManage, PermGradeApprove:
+		return false
+	}
+	return HasPermission(rolePermissions[entity.RoleAdmin], perm)
+}
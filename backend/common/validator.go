@@ -14,34 +14,44 @@ func Validator[T any](c *fiber.Ctx) (*T, error) {
 		return nil, errors.New("Invalid request")
 	}
 
+	if err := ValidateStruct(body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// ValidateStruct checks the struct against its validate tags, for values that do not come from the request body
+// as a whole such as the rows of a batch or an imported file.
+func ValidateStruct(body any) error {
 	var validate = validator.New(validator.WithRequiredStructEnabled())
 	if errs := validate.Struct(body); errs != nil {
 		err := errs.(validator.ValidationErrors)[0]
 		switch err.Tag() {
 		case "required":
-			return nil, errors.New(fmt.Sprintf("%s không được để trống", err.Field()))
+			return errors.New(fmt.Sprintf("%s không được để trống", err.Field()))
 		case "email":
-			return nil, errors.New(fmt.Sprintf("%s không phải định dạng email ", err.Field()))
+			return errors.New(fmt.Sprintf("%s không phải định dạng email ", err.Field()))
 		case "len":
-			return nil, errors.New(fmt.Sprintf("%s phải dài chính xác %v ký tự", err.Field(), err.Param()))
+			return errors.New(fmt.Sprintf("%s phải dài chính xác %v ký tự", err.Field(), err.Param()))
 		case "min":
-			return nil, errors.New(fmt.Sprintf("%s phải có ít nhất %v ký tự", err.Field(), err.Param()))
+			return errors.New(fmt.Sprintf("%s phải có ít nhất %v ký tự", err.Field(), err.Param()))
 		case "max":
-			return nil, errors.New(fmt.Sprintf("%s không được dài hơn %v ký tự", err.Field(), err.Param()))
+			return errors.New(fmt.Sprintf("%s không được dài hơn %v ký tự", err.Field(), err.Param()))
 		case "alphanumunicode":
-			return nil, errors.New(fmt.Sprintf("%s chỉ được phép chứa ký tự hoặc là số", err.Field()))
+			return errors.New(fmt.Sprintf("%s chỉ được phép chứa ký tự hoặc là số", err.Field()))
 		case "alphaunicode":
-			return nil, errors.New(fmt.Sprintf("%s chỉ được phép là ký tự", err.Field()))
+			return errors.New(fmt.Sprintf("%s chỉ được phép là ký tự", err.Field()))
 		case "number":
-			return nil, errors.New(fmt.Sprintf("%s chỉ được phép là số", err.Field()))
+			return errors.New(fmt.Sprintf("%s chỉ được phép là số", err.Field()))
 		case "gte":
-			return nil, errors.New(fmt.Sprintf("%s phải nhiều hơn hoặc bằng %v", err.Field(), err.Param()))
+			return errors.New(fmt.Sprintf("%s phải nhiều hơn hoặc bằng %v", err.Field(), err.Param()))
 		case "lte":
-			return nil, errors.New(fmt.Sprintf("%s phải ít hơn hoặc bằng %v", err.Field(), err.Param()))
+			return errors.New(fmt.Sprintf("%s phải ít hơn hoặc bằng %v", err.Field(), err.Param()))
 		default:
-			return nil, errors.New(fmt.Sprintf("%s: %v must satisfy %s %v criteria", err.Field(), err.Value(), err.Tag(), err.Param()))
+			return errors.New(fmt.Sprintf("%s: %v must satisfy %s %v criteria", err.Field(), err.Value(), err.Tag(), err.Param()))
 		}
 	}
 
-	return body, nil
+	return nil
 }
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"sort"
+)
+
+type gradeAttempt struct {
+	entity.Grade
+	Counted    bool   `json:"counted"`
+	ResultText string `json:"result_text"`
+}
+
+// subjectAttempts is the attempt history of a student in one subject, oldest attempt first.
+type subjectAttempts struct {
+	SubjectID   string         `json:"subject_id"`
+	SubjectName string         `json:"subject_name"`
+	Attempts    []gradeAttempt `json:"attempts"`
+}
+
+type studentDetail struct {
+	entity.Student
+	AcademicSummary common.AcademicSummary `json:"academic_summary"`
+	AttemptHistory  []subjectAttempts      `json:"attempt_history"`
+}
+
+// studentAcademicRecord is computed from the current grades on every call, so it always reflects grade updates and deletes.
+// It returns the academic summary and the attempt history of the grades of one student.
+func studentAcademicRecord(grades []entity.Grade) (common.AcademicSummary, []subjectAttempts, error) {
+	if err := fillGradeResults(grades); err != nil {
+		return common.AcademicSummary{}, nil, err
+	}
+
+	subjectsId := make([]string, 0, len(grades))
+	termsId := make([]uint, 0, len(grades))
+	for _, grade := range grades {
+		subjectsId = append(subjectsId, grade.SubjectID)
+		if grade.TermID != nil {
+			termsId = append(termsId, *grade.TermID)
+		}
+	}
+
+	subjects := make([]entity.Subject, 0)
+	if len(subjectsId) > 0 {
+		if err := common.DBConn.Select("id", "name", "credits").Find(&subjects, "id IN ?", subjectsId).Error; err != nil {
+			return common.AcademicSummary{}, nil, err
+		}
+	}
+
+	subjectsById := make(map[string]entity.Subject, len(subjects))
+	for _, subject := range subjects {
+		subjectsById[subject.ID] = subject
+	}
+
+	terms := make([]entity.AcademicTerm, 0)
+	if len(termsId) > 0 {
+		if err := common.DBConn.Find(&terms, "id IN ?", termsId).Error; err != nil {
+			return common.AcademicSummary{}, nil, err
+		}
+	}
+
+	termCodes := make(map[uint]string, len(terms))
+	for _, term := range terms {
+		termCodes[term.ID] = term.Code()
+	}
+
+	// Incomplete and exempt grades stay in the history but have no total to average
+	gpaGrades := make([]entity.Grade, 0, len(grades))
+	for _, grade := range grades {
+		if common.CountsTowardGPA(grade) {
+			gpaGrades = append(gpaGrades, grade)
+		}
+	}
+
+	counted := common.CountedAttempts(gpaGrades, common.AttemptPolicy())
+
+	records := make([]common.AcademicRecord, 0, len(gpaGrades))
+	for _, grade := range gpaGrades {
+		term := common.GradeTerm(grade.CreatedAt)
+		if grade.TermID != nil {
+			term = termCodes[*grade.TermID]
+		}
+
+		records = append(records, common.AcademicRecord{
+			Term:       term,
+			Credits:    int(subjectsById[grade.SubjectID].Credits),
+			TotalScore: grade.TotalScore,
+			GPAScore:   grade.GPAScore,
+			Passed:     grade.Passed,
+			Counted:    counted[grade.ID],
+		})
+	}
+
+	history := make([]subjectAttempts, 0)
+	historyIndex := make(map[string]int)
+	for _, grade := range grades {
+		idx, ok := historyIndex[grade.SubjectID]
+		if !ok {
+			idx = len(history)
+			historyIndex[grade.SubjectID] = idx
+			history = append(history, subjectAttempts{
+				SubjectID:   grade.SubjectID,
+				SubjectName: subjectsById[grade.SubjectID].Name,
+			})
+		}
+		history[idx].Attempts = append(history[idx].Attempts, gradeAttempt{
+			Grade:      grade,
+			Counted:    counted[grade.ID],
+			ResultText: common.GradeResultText(grade),
+		})
+	}
+
+	for _, subject := range history {
+		sort.Slice(subject.Attempts, func(i, j int) bool {
+			if subject.Attempts[i].Attempt != subject.Attempts[j].Attempt {
+				return subject.Attempts[i].Attempt < subject.Attempts[j].Attempt
+			}
+			return subject.Attempts[i].CreatedAt.Before(subject.Attempts[j].CreatedAt)
+		})
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].SubjectID < history[j].SubjectID
+	})
+
+	return common.SummarizeAcademic(records), history, nil
+}
+
+func findStudentWithGrades(c *fiber.Ctx, studentId string) (*entity.Student, error) {
+	var student entity.Student
+
+	if err := common.DBConn.Preload("Grades").First(&student, "id = ?", studentId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy sinh viên")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := checkDepartmentScope(c, student.DepartmentID); err != nil {
+		return nil, err
+	}
+
+	return &student, nil
+}
+
+// [GET] /api/students/:id/academic-summary
+func StudentAcademicSummaryGetById(c *fiber.Ctx) error {
+	student, err := findStudentWithGrades(c, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	summary, _, err := studentAcademicRecord(student.Grades)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", summary))
+}
+
+// [GET] /api/students/:id/attempts
+func StudentAttemptGetById(c *fiber.Ctx) error {
+	student, err := findStudentWithGrades(c, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	_, history, err := studentAcademicRecord(student.Grades)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", history))
+}
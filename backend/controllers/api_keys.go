@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"strconv"
+	"time"
+)
+
+type apiKeyCreated struct {
+	entity.APIKey
+	// Key is only returned once, when the key is created
+	Key string `json:"key"`
+}
+
+// [GET] /api/api-keys
+func APIKeyGetAll(c *fiber.Ctx) error {
+	var apiKeys []entity.APIKey
+
+	if err := common.DBConn.Preload("Departments").Order("id desc").Find(&apiKeys).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", apiKeys))
+}
+
+// [POST] /api/api-keys
+func APIKeyCreate(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.APIKeyCreate](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	for _, perm := range bodyData.Permissions {
+		if !common.IsAPIKeyPermission(perm) {
+			return fiber.NewError(fiber.StatusBadRequest, "Quyền không hợp lệ cho API key: "+perm)
+		}
+	}
+
+	if bodyData.ExpiresAt != nil && bodyData.ExpiresAt.Before(time.Now()) {
+		return fiber.NewError(fiber.StatusBadRequest, "Thời hạn của API key phải ở tương lai")
+	}
+
+	departments, err := findDepartments(bodyData.DepartmentIDs)
+	if err != nil {
+		return err
+	}
+
+	secret, err := common.GenerateRandToken(32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo API key")
+	}
+
+	key := "tdt_" + secret
+	currentUserId, _ := c.Locals("currentUserId").(string)
+	createdById, _ := strconv.Atoi(currentUserId)
+
+	newAPIKey := entity.APIKey{
+		Name:        bodyData.Name,
+		Prefix:      key[:12],
+		KeyHash:     common.HashToken(key),
+		Permissions: bodyData.Permissions,
+		ExpiresAt:   bodyData.ExpiresAt,
+		CreatedByID: uint(createdById),
+		Departments: departments,
+	}
+
+	if err := common.DBConn.Create(&newAPIKey).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo API key")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(common.NewResponse(fiber.StatusCreated, "Success", apiKeyCreated{
+		APIKey: newAPIKey,
+		Key:    key,
+	}))
+}
+
+// [DELETE] /api/api-keys/:id
+func APIKeyDeleteById(c *fiber.Ctx) error {
+	apiKeyId := c.Params("id")
+
+	// Revoking only marks the key, user sessions are untouched
+	result := common.DBConn.Model(&entity.APIKey{}).Where("id = ? and revoked_at IS NULL", apiKeyId).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi thu hồi API key")
+	}
+
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy API key")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", nil))
+}
@@ -14,7 +14,7 @@ import (
 func AssignmentGetAll(c *fiber.Ctx) error {
 	var assignments []entity.InstructorAssignment
 
-	if err := common.DBConn.Find(&assignments).Error; err != nil {
+	if err := common.DBConn.Scopes(scopeSubjectDepartment(c, "subject_id"), scopeTerm(c)).Find(&assignments).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 	return c.JSON(common.NewResponse(
@@ -27,6 +27,10 @@ func AssignmentGetAll(c *fiber.Ctx) error {
 func AssignmentGetAllByDepartmentId(c *fiber.Ctx) error {
 	departmentId := c.Params("id")
 
+	if err := checkDepartmentScopeById(c, departmentId); err != nil {
+		return err
+	}
+
 	var subjectsId []string
 
 	if err := common.DBConn.Model(&entity.Subject{}).Select("id").Where("department_id = ?", departmentId).Find(&subjectsId).Error; err != nil {
@@ -34,7 +38,7 @@ func AssignmentGetAllByDepartmentId(c *fiber.Ctx) error {
 	}
 
 	var assignments []entity.InstructorAssignment
-	if err := common.DBConn.Where("subject_id IN ?", subjectsId).Find(&assignments).Error; err != nil {
+	if err := common.DBConn.Where("subject_id IN ?", subjectsId).Scopes(scopeTerm(c)).Find(&assignments).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -60,7 +64,7 @@ func AssignmentGetAllInstructorByFullName(c *fiber.Ctx) error {
 	}
 
 	var assignments []entity.InstructorAssignment
-	if err := common.DBConn.Where("instructor_id IN ?", instructorsId).Find(&assignments).Error; err != nil {
+	if err := common.DBConn.Where("instructor_id IN ?", instructorsId).Scopes(scopeSubjectDepartment(c, "subject_id"), scopeTerm(c)).Find(&assignments).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -85,6 +89,10 @@ func AssignmentCreate(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, subject.DepartmentID); err != nil {
+		return err
+	}
+
 	var instructor entity.Instructor
 	if err := common.DBConn.First(&instructor, "id = ?", bodyData.InstructorID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -97,8 +105,13 @@ func AssignmentCreate(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Giảng viên không thuộc khoa của môn học")
 	}
 
+	term, err := openTerm(bodyData.TermID)
+	if err != nil {
+		return err
+	}
+
 	var assignment entity.InstructorAssignment
-	if err := common.DBConn.First(&assignment, "subject_id = ? AND instructor_id = ?", subject.ID, instructor.ID).Error; err != nil {
+	if err := common.DBConn.First(&assignment, "subject_id = ? AND instructor_id = ? AND term_id = ?", subject.ID, instructor.ID, term.ID).Error; err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Lỗi khi truy vấn cơ sở dữ liệu")
 		}
@@ -111,6 +124,7 @@ func AssignmentCreate(c *fiber.Ctx) error {
 	newAssignment := entity.InstructorAssignment{
 		SubjectID:    bodyData.SubjectID,
 		InstructorID: bodyData.InstructorID,
+		TermID:       &term.ID,
 	}
 
 	if err := common.DBConn.Create(&newAssignment).Error; err != nil {
@@ -148,6 +162,10 @@ func AssignmentUpdateById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkSubjectScope(c, assignment.SubjectID); err != nil {
+		return err
+	}
+
 	var subject entity.Subject
 	if err := common.DBConn.First(&subject, "id = ?", bodyData.SubjectID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -156,6 +174,10 @@ func AssignmentUpdateById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, subject.DepartmentID); err != nil {
+		return err
+	}
+
 	var instructor entity.Instructor
 	if err := common.DBConn.First(&instructor, "id = ?", bodyData.InstructorID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -168,8 +190,13 @@ func AssignmentUpdateById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Giảng viên không thuộc khoa của môn học")
 	}
 
+	termId, err := updatedTerm(assignment.TermID, bodyData.TermID)
+	if err != nil {
+		return err
+	}
+
 	var existAssignment entity.InstructorAssignment
-	if err := common.DBConn.First(&existAssignment, "subject_id = ? AND instructor_id = ?", subject.ID, instructor.ID).Error; err != nil {
+	if err := common.DBConn.Scopes(scopeTermId(termId)).First(&existAssignment, "subject_id = ? AND instructor_id = ? AND id <> ?", subject.ID, instructor.ID, assignment.ID).Error; err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Lỗi khi truy vấn cơ sở dữ liệu")
 		}
@@ -181,6 +208,7 @@ func AssignmentUpdateById(c *fiber.Ctx) error {
 
 	assignment.SubjectID = subject.ID
 	assignment.InstructorID = instructor.ID
+	assignment.TermID = termId
 
 	if err := common.DBConn.Save(&assignment).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật phân công")
@@ -200,6 +228,10 @@ func AssignmentDeleteById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkSubjectScope(c, assignment.SubjectID); err != nil {
+		return err
+	}
+
 	if err := common.DBConn.Delete(&assignment).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa phân công")
 	}
@@ -1,24 +1,40 @@
 package controllers
 
 import (
+	"crypto/subtle"
 	"errors"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"os"
 	"qldiemsv/common"
 	"qldiemsv/models/entity"
 	"qldiemsv/models/req"
 	"strconv"
+	"strings"
 	"time"
 )
 
-func createJWT(userId uint) (string, error) {
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), 11)
+
+func createJWT(userId uint, role string, sessionId string) (string, error) {
+	tokenId, tokenIdErr := common.GenerateRandToken(16)
+
+	if tokenIdErr != nil {
+		return "", errors.New("Có lỗi trong khi tạo token")
+	}
+
 	// Create the Claims
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"uid": strconv.Itoa(int(userId)),
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
+		"uid":  strconv.Itoa(int(userId)),
+		"role": role,
+		"sid":  sessionId,
+		"jti":  tokenId,
+		"iat":  now.Unix(),
+		"exp":  now.Add(common.GetEnvDuration("JWT_ACCESS_TTL", 15*time.Minute)).Unix(),
 	}
 
 	// Create token
@@ -32,6 +48,56 @@ func createJWT(userId uint) (string, error) {
 	return tokenSignedString, nil
 }
 
+// createRefreshToken returns a new "<session id>.<secret>" refresh token and the hash stored for it.
+func createRefreshToken(sessionId string) (string, string, error) {
+	secret, err := common.GenerateRandToken(32)
+
+	if err != nil {
+		return "", "", errors.New("Có lỗi trong khi tạo token")
+	}
+
+	return sessionId + "." + secret, common.HashToken(secret), nil
+}
+
+// issueSession starts a new session for the user and sets the access and refresh tokens on the response.
+func issueSession(c *fiber.Ctx, user entity.User) error {
+	sessionId, err := common.GenerateRandToken(16)
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo token")
+	}
+
+	refreshToken, refreshTokenHash, err := createRefreshToken(sessionId)
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	newSession := entity.UserSession{
+		ID:               sessionId,
+		RefreshTokenHash: refreshTokenHash,
+		IP:               c.IP(),
+		UserAgent:        c.Get(fiber.HeaderUserAgent),
+		ExpiresAt:        time.Now().Add(common.GetEnvDuration("JWT_REFRESH_TTL", 30*24*time.Hour)),
+		UserID:           user.ID,
+	}
+
+	if err := common.DBConn.Create(&newSession).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo phiên đăng nhập")
+	}
+
+	token, tokenIsErr := createJWT(user.ID, user.Role, sessionId)
+
+	if tokenIsErr != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, tokenIsErr.Error())
+	}
+
+	c.Set(os.Getenv("JWT_HEADER"), token)
+	c.Set(os.Getenv("JWT_REFRESH_HEADER"), refreshToken)
+
+	return nil
+}
+
 func AuthLogin(c *fiber.Ctx) error {
 	bodyData, err := common.Validator[req.AuthLogin](c)
 
@@ -39,47 +105,75 @@ func AuthLogin(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	if err := checkLoginThrottle(loginThrottleKeys(c, bodyData.UserName)); err != nil {
+		return err
+	}
+
 	var userRecord entity.User
 
 	if err := common.DBConn.First(&userRecord, "user_name = ?", bodyData.UserName).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fiber.NewError(fiber.StatusBadRequest, "Username không tồn tại")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
 		}
-		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+		// Compare against a dummy hash so an unknown username takes as long as a wrong password
+		userRecord.Password = string(dummyPasswordHash)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(userRecord.Password), []byte(bodyData.Password)); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Mật khẩu không đúng")
+	if err := bcrypt.CompareHashAndPassword([]byte(userRecord.Password), []byte(bodyData.Password)); err != nil || userRecord.ID == 0 {
+		if err := recordLoginFailures(c, bodyData.UserName); err != nil {
+			return err
+		}
+		return fiber.NewError(fiber.StatusBadRequest, "Tên đăng nhập hoặc mật khẩu không đúng")
 	}
 
-	token, tokenIsErr := createJWT(userRecord.ID)
+	// The failures are only cleared once the second factor is verified as well
+	if userRecord.TOTPEnabled || twoFactorRequired(userRecord) {
+		challengeToken, err := createChallengeJWT(userRecord.ID)
 
-	if tokenIsErr != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, tokenIsErr.Error())
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(common.NewResponse(fiber.StatusOK, "Vui lòng xác thực hai bước", twoFactorChallenge{
+			ChallengeToken: challengeToken,
+			TOTPEnabled:    userRecord.TOTPEnabled,
+		}))
 	}
 
-	c.Set(os.Getenv("JWT_HEADER"), token)
+	if err := clearLoginFailures(c, bodyData.UserName); err != nil {
+		return err
+	}
+
+	if err := issueSession(c, userRecord); err != nil {
+		return err
+	}
 
 	return c.Status(fiber.StatusOK).JSON(common.NewResponse(fiber.StatusOK, "Đăng nhập thành công", userRecord))
 }
 
 func AuthRegister(c *fiber.Ctx) error {
+	// Accounts are created by admins or through invitations unless self-registration is switched on
+	if !common.GetEnvBool("ALLOW_SELF_REGISTRATION", false) {
+		return fiber.NewError(fiber.StatusForbidden, "Đăng ký tài khoản đã bị tắt, vui lòng liên hệ quản trị viên")
+	}
+
 	bodyData, err := common.Validator[req.AuthRegister](c)
 
 	if err != nil || bodyData == nil {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	var userRecord entity.User
+	if err := common.ValidatePassword(bodyData.Password); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 
-	if err := common.DBConn.First(&userRecord, "user_name = ?", bodyData.UserName).Error; err != nil {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
-		}
+	if err := checkUserUnique(bodyData.UserName, bodyData.Email); err != nil {
+		return err
 	}
 
-	if userRecord != (entity.User{}) {
-		return fiber.NewError(fiber.StatusBadRequest, "Username đã tồn tại")
+	var email *string
+	if bodyData.Email != "" {
+		email = &bodyData.Email
 	}
 
 	hashPassword, hashPasswordErr := bcrypt.GenerateFromPassword([]byte(bodyData.Password), 11)
@@ -92,21 +186,19 @@ func AuthRegister(c *fiber.Ctx) error {
 		FirstName: bodyData.FirstName,
 		LastName:  bodyData.LastName,
 		UserName:  bodyData.UserName,
+		Email:     email,
 		Password:  string(hashPassword),
+		Role:      entity.RoleStudent,
 	}
 
 	if err := common.DBConn.Create(&newUser).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo tài khoản")
 	}
 
-	token, tokenIsErr := createJWT(newUser.ID)
-
-	if tokenIsErr != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, tokenIsErr.Error())
+	if err := issueSession(c, newUser); err != nil {
+		return err
 	}
 
-	c.Set(os.Getenv("JWT_HEADER"), token)
-
 	return c.Status(fiber.StatusCreated).JSON(common.NewResponse(fiber.StatusOK, "Đăng ký thành công", newUser))
 }
 
@@ -132,3 +224,130 @@ func AuthVerify(c *fiber.Ctx) error {
 		userRecord),
 	)
 }
+
+// [POST] /api/auth/refresh
+func AuthRefresh(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.AuthRefresh](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	sessionId, secret, found := strings.Cut(bodyData.RefreshToken, ".")
+	if !found {
+		return fiber.NewError(fiber.StatusUnauthorized, "Refresh token không hợp lệ")
+	}
+
+	var session entity.UserSession
+	if err := common.DBConn.First(&session, "id = ?", sessionId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusUnauthorized, "Refresh token không hợp lệ")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Phiên đăng nhập đã hết hạn")
+	}
+
+	// A refresh token that was already rotated away means it leaked, so the whole session is revoked
+	if subtle.ConstantTimeCompare([]byte(common.HashToken(secret)), []byte(session.RefreshTokenHash)) != 1 {
+		now := time.Now()
+		if err := common.DBConn.Model(&session).Update("revoked_at", &now).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+		}
+		return fiber.NewError(fiber.StatusUnauthorized, "Refresh token không hợp lệ")
+	}
+
+	var userRecord entity.User
+	if err := common.DBConn.First(&userRecord, "id = ?", session.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusUnauthorized, "Không tìm thấy user")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	refreshToken, refreshTokenHash, err := createRefreshToken(session.ID)
+
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	// Only the first of two concurrent refreshes with the same token may rotate it
+	rotateResult := common.DBConn.Model(&entity.UserSession{}).
+		Where("id = ? and refresh_token_hash = ?", session.ID, session.RefreshTokenHash).
+		Updates(entity.UserSession{RefreshTokenHash: refreshTokenHash, IP: c.IP()})
+
+	if rotateResult.Error != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	if rotateResult.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusUnauthorized, "Refresh token không hợp lệ")
+	}
+
+	token, tokenIsErr := createJWT(userRecord.ID, userRecord.Role, session.ID)
+
+	if tokenIsErr != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, tokenIsErr.Error())
+	}
+
+	c.Set(os.Getenv("JWT_HEADER"), token)
+	c.Set(os.Getenv("JWT_REFRESH_HEADER"), refreshToken)
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", nil))
+}
+
+// [POST] /api/auth/logout
+func AuthLogout(c *fiber.Ctx) error {
+	currentSessionId, currentSessionIdIsOk := c.Locals("currentSessionId").(string)
+
+	if !currentSessionIdIsOk {
+		return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	if err := revokeCurrentToken(c); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi đăng xuất")
+	}
+
+	if err := common.DBConn.Model(&entity.UserSession{}).Where("id = ? and revoked_at IS NULL", currentSessionId).Update("revoked_at", time.Now()).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi đăng xuất")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Đăng xuất thành công", nil))
+}
+
+// revokeCurrentToken rejects the access token of the request by its ID until it expires, and drops the revoked
+// tokens that have expired since.
+func revokeCurrentToken(c *fiber.Ctx) error {
+	tokenId, ok := c.Locals("currentTokenId").(string)
+	if !ok {
+		return nil
+	}
+	expiresAt, _ := c.Locals("currentTokenExpiresAt").(time.Time)
+
+	if err := common.DBConn.Where("expires_at < ?", time.Now()).Delete(&entity.RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	return common.DBConn.Clauses(clause.OnConflict{DoNothing: true}).Create(&entity.RevokedToken{ID: tokenId, ExpiresAt: expiresAt}).Error
+}
+
+// [POST] /api/auth/logout/all
+func AuthLogoutAll(c *fiber.Ctx) error {
+	currentUserId, currentUserIdIsOk := c.Locals("currentUserId").(string)
+
+	if !currentUserIdIsOk {
+		return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	if err := revokeCurrentToken(c); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi đăng xuất")
+	}
+
+	if err := common.DBConn.Model(&entity.UserSession{}).Where("user_id = ? and revoked_at IS NULL", currentUserId).Update("revoked_at", time.Now()).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi đăng xuất")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Đăng xuất khỏi tất cả phiên thành công", nil))
+}
@@ -26,7 +26,7 @@ func generateClassID(departmentID uint) string {
 func ClassGetAll(c *fiber.Ctx) error {
 	var classes []entity.Class
 
-	if err := common.DBConn.Preload("Students").Find(&classes).Error; err != nil {
+	if err := common.DBConn.Preload("Students").Scopes(scopeDepartment(c, "department_id")).Find(&classes).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -48,12 +48,21 @@ func ClassGetById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, class.DepartmentID); err != nil {
+		return err
+	}
+
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", class))
 }
 
 // [GET] /api/classes/department/:departmentID
 func GetClassesByDepartmentID(c *fiber.Ctx) error {
 	departmentID := c.Params("departmentID")
+
+	if err := checkDepartmentScopeById(c, departmentID); err != nil {
+		return err
+	}
+
 	var classes []entity.Class
 
 	if err := common.DBConn.Preload("Students").Find(&classes, "department_id = ?", departmentID).Error; err != nil {
@@ -74,6 +83,10 @@ func ClassCreate(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	if err := checkDepartmentScope(c, bodyData.DepartmentID); err != nil {
+		return err
+	}
+
 	var department entity.Department
 
 	if err := common.DBConn.Select("symbol").First(&department, "id = ?", bodyData.DepartmentID).Error; err != nil {
@@ -170,6 +183,10 @@ func ClassUpdateById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, class.DepartmentID); err != nil {
+		return err
+	}
+
 	var instructor entity.Instructor
 	if err := common.DBConn.First(&instructor, "id = ?", bodyData.HostInstructorID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -205,6 +222,10 @@ func ClassDeleteById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy lớp")
 	}
 
+	if err := checkDepartmentScope(c, class.DepartmentID); err != nil {
+		return err
+	}
+
 	if err := common.DBConn.Delete(&class).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa lớp")
 	}
@@ -214,7 +235,7 @@ func ClassDeleteById(c *fiber.Ctx) error {
 
 // [DELETE] /api/classes
 func ClassDeleteAll(c *fiber.Ctx) error {
-	if err := common.DBConn.Where("1 = 1").Delete(&entity.Class{}).Error; err != nil {
+	if err := common.DBConn.Where("1 = 1").Scopes(scopeDepartment(c, "department_id")).Delete(&entity.Class{}).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa tất cả lớp")
 	}
 
@@ -229,7 +250,7 @@ func ClassDeleteByListId(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	if err := common.DBConn.Where("id IN ?", bodyData.ListId).Delete(&entity.Class{}).Error; err != nil {
+	if err := common.DBConn.Where("id IN ?", bodyData.ListId).Scopes(scopeDepartment(c, "department_id")).Delete(&entity.Class{}).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy lớp")
 		}
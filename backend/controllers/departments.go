@@ -9,11 +9,34 @@ import (
 	"qldiemsv/models/req"
 )
 
+// findDepartments loads the departments of the IDs, an ID given twice counts once.
+func findDepartments(departmentIds []uint) ([]entity.Department, error) {
+	unique := make(map[uint]bool, len(departmentIds))
+	for _, id := range departmentIds {
+		unique[id] = true
+	}
+
+	departments := make([]entity.Department, 0, len(unique))
+	if len(unique) == 0 {
+		return departments, nil
+	}
+
+	if err := common.DBConn.Find(&departments, "id IN ?", departmentIds).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if len(departments) != len(unique) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy khoa")
+	}
+
+	return departments, nil
+}
+
 // [GET] /api/departments
 func DepartmentGetAll(c *fiber.Ctx) error {
 	var departments []entity.Department
 
-	if err := common.DBConn.Preload("Instructors").Preload("Subjects").Preload("Classes").Preload("Students").Find(&departments).Error; err != nil {
+	if err := common.DBConn.Preload("Instructors").Preload("Subjects").Preload("Classes").Preload("Students").Scopes(scopeDepartment(c, "id")).Find(&departments).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -31,6 +54,10 @@ func DepartmentCreate(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	if err := checkDepartmentScope(c, bodyData.ID); err != nil {
+		return err
+	}
+
 	var department entity.Department
 
 	if err := common.DBConn.Select("id").First(&department, "id = ? or symbol = ?", bodyData.ID, bodyData.Symbol).Error; err != nil {
@@ -71,6 +98,10 @@ func DepartmentGetById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, department.ID); err != nil {
+		return err
+	}
+
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", department))
 }
 
@@ -93,6 +124,10 @@ func DepartmentUpdateById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, department.ID); err != nil {
+		return err
+	}
+
 	department.Name = bodyData.Name
 
 	if err := common.DBConn.Save(&department).Error; err != nil {
@@ -114,6 +149,10 @@ func DepartmentDeleteById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, department.ID); err != nil {
+		return err
+	}
+
 	if err := common.DBConn.Delete(&department).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa khoa")
 	}
@@ -129,7 +168,7 @@ func DepartmentDeleteByListId(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	if err := common.DBConn.Where("id IN ?", bodyData.ListId).Delete(&entity.Department{}).Error; err != nil {
+	if err := common.DBConn.Where("id IN ?", bodyData.ListId).Scopes(scopeDepartment(c, "id")).Delete(&entity.Department{}).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy khoa")
 		}
@@ -141,7 +180,7 @@ func DepartmentDeleteByListId(c *fiber.Ctx) error {
 
 // [DELETE] /api/departments
 func DepartmentDeleteAll(c *fiber.Ctx) error {
-	if err := common.DBConn.Where("1 = 1").Delete(&entity.Department{}).Error; err != nil {
+	if err := common.DBConn.Where("1 = 1").Scopes(scopeDepartment(c, "id")).Delete(&entity.Department{}).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa tất cả khoa")
 	}
 
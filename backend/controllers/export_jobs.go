@@ -0,0 +1,582 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"os"
+	"path/filepath"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"strconv"
+	"time"
+)
+
+const (
+	// Workers look for queued jobs at this interval, and right away when a job is created
+	exportJobPollInterval    = 5 * time.Second
+	exportJobCleanupInterval = 10 * time.Minute
+	exportJobErrorMaxLength  = 255
+
+	// A running job whose instance has not beaten for exportJobStaleAfter is taken to be lost with its instance
+	exportJobHeartbeatInterval = 30 * time.Second
+	exportJobStaleAfter        = 2 * time.Minute
+)
+
+// errExportJobCancelled stops a running job that was cancelled, or requeued as stale.
+var errExportJobCancelled = errors.New("export job cancelled")
+
+// exportJobWake wakes one idle worker when a job is queued.
+var exportJobWake = make(chan struct{}, 1)
+
+// exportInstanceId identifies this instance in the jobs it runs, it should stay the same across restarts.
+var exportInstanceId string
+
+type exportJobLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func exportDir() string {
+	return common.GetEnvString("EXPORT_DIR", "static/exports")
+}
+
+// scopeExportJobOwner limits a query to the jobs created by the current user or API key.
+func scopeExportJobOwner(c *fiber.Ctx) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if apiKeyId, ok := c.Locals("currentApiKeyId").(uint); ok {
+			return db.Where("api_key_id = ?", apiKeyId)
+		}
+		currentUserId, _ := c.Locals("currentUserId").(string)
+		userId, err := strconv.ParseUint(currentUserId, 10, 64)
+		if err != nil {
+			return db.Where("1 = 0")
+		}
+		return db.Where("user_id = ?", userId)
+	}
+}
+
+func findExportJob(c *fiber.Ctx) (*entity.ExportJob, error) {
+	var job entity.ExportJob
+	if err := common.DBConn.Scopes(scopeExportJobOwner(c)).First(&job, "id = ?", c.Params("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy công việc xuất file")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+	return &job, nil
+}
+
+// exportJobParams checks the target of the export against the department scope of the request, the scope is kept
+// in the params so the job exports the same rows the requester could see.
+func exportJobParams(c *fiber.Ctx, bodyData *req.ExportJobCreate) (entity.ExportJobParams, error) {
+	params := entity.ExportJobParams{TermID: bodyData.TermID}
+	params.DepartmentIds, params.Scoped = departmentScope(c)
+
+	switch bodyData.Kind {
+	case entity.ExportKindGradesByDepartment:
+		if bodyData.DepartmentID == 0 {
+			return params, fiber.NewError(fiber.StatusBadRequest, "DepartmentID không được để trống")
+		}
+		var department entity.Department
+		if err := common.DBConn.Select("id").First(&department, "id = ?", bodyData.DepartmentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return params, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy khoa")
+			}
+			return params, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+		if err := checkDepartmentScope(c, department.ID); err != nil {
+			return params, err
+		}
+		params.DepartmentID = department.ID
+	case entity.ExportKindGradesByClass:
+		if bodyData.ClassID == "" {
+			return params, fiber.NewError(fiber.StatusBadRequest, "ClassID không được để trống")
+		}
+		if err := checkExportClass(c, bodyData.ClassID); err != nil {
+			return params, err
+		}
+		params.ClassID = bodyData.ClassID
+	case entity.ExportKindGradesBySubject:
+		if bodyData.SubjectID == "" {
+			return params, fiber.NewError(fiber.StatusBadRequest, "SubjectID không được để trống")
+		}
+		var subject entity.Subject
+		if err := common.DBConn.Select("id", "department_id").First(&subject, "id = ?", bodyData.SubjectID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return params, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy môn học")
+			}
+			return params, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+		if err := checkDepartmentScope(c, subject.DepartmentID); err != nil {
+			return params, err
+		}
+		params.SubjectID = subject.ID
+	case entity.ExportKindTranscripts:
+		// Transcripts hold the whole record of the students, not only the grades
+		granted, _ := c.Locals("currentPermissions").([]string)
+		if !common.HasPermission(granted, common.PermStudentRead) {
+			return params, fiber.NewError(fiber.StatusForbidden, "Bạn không có quyền thực hiện thao tác này")
+		}
+
+		switch {
+		case bodyData.StudentID != "":
+			var student entity.Student
+			if err := common.DBConn.Select("id", "department_id").First(&student, "id = ?", bodyData.StudentID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return params, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy sinh viên")
+				}
+				return params, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+			}
+			if err := checkDepartmentScope(c, student.DepartmentID); err != nil {
+				return params, err
+			}
+			params.StudentID = student.ID
+		case bodyData.ClassID != "":
+			if err := checkExportClass(c, bodyData.ClassID); err != nil {
+				return params, err
+			}
+			params.ClassID = bodyData.ClassID
+		default:
+			return params, fiber.NewError(fiber.StatusBadRequest, "StudentID hoặc ClassID không được để trống")
+		}
+		// A transcript covers every term
+		params.TermID = 0
+	}
+
+	if params.TermID != 0 {
+		var term entity.AcademicTerm
+		if err := common.DBConn.Select("id").First(&term, "id = ?", params.TermID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return params, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy học kỳ")
+			}
+			return params, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+	}
+
+	return params, nil
+}
+
+func checkExportClass(c *fiber.Ctx, classId string) error {
+	var class entity.Class
+	if err := common.DBConn.Select("id", "department_id").First(&class, "id = ?", classId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy lớp")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+	return checkDepartmentScope(c, class.DepartmentID)
+}
+
+// [POST] /api/jobs/exports
+func ExportJobCreate(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.ExportJobCreate](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	params, err := exportJobParams(c, bodyData)
+	if err != nil {
+		return err
+	}
+
+	job := entity.ExportJob{
+		Kind:   bodyData.Kind,
+		Params: params,
+		Status: entity.ExportJobQueued,
+	}
+
+	if apiKeyId, ok := c.Locals("currentApiKeyId").(uint); ok {
+		job.APIKeyID = &apiKeyId
+	} else {
+		userId, err := requestUserId(c)
+		if err != nil {
+			return err
+		}
+		job.UserID = &userId
+	}
+
+	if err := common.DBConn.Create(&job).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo công việc xuất file")
+	}
+
+	wakeExportWorker()
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", job))
+}
+
+// [GET] /api/jobs
+func ExportJobGetAll(c *fiber.Ctx) error {
+	var jobs []entity.ExportJob
+
+	query := common.DBConn.Scopes(scopeExportJobOwner(c))
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Order("id desc").Find(&jobs).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", jobs))
+}
+
+// [GET] /api/jobs/:id
+func ExportJobGetById(c *fiber.Ctx) error {
+	job, err := findExportJob(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", job))
+}
+
+// [POST] /api/jobs/:id/cancel
+func ExportJobCancelById(c *fiber.Ctx) error {
+	job, err := findExportJob(c)
+	if err != nil {
+		return err
+	}
+
+	// A running job sees the new status at its next progress update and stops there
+	result := common.DBConn.Model(&entity.ExportJob{}).
+		Where("id = ? AND status IN ?", job.ID, []string{entity.ExportJobQueued, entity.ExportJobRunning}).
+		Updates(map[string]interface{}{"status": entity.ExportJobCancelled, "finished_at": time.Now()})
+	if result.Error != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi hủy công việc xuất file")
+	}
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Chỉ có thể hủy công việc đang chờ hoặc đang chạy")
+	}
+
+	job, err = findExportJob(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", job))
+}
+
+// [POST] /api/jobs/:id/link
+func ExportJobLinkById(c *fiber.Ctx) error {
+	job, err := findExportJob(c)
+	if err != nil {
+		return err
+	}
+
+	if job.Status != entity.ExportJobSucceeded {
+		return fiber.NewError(fiber.StatusBadRequest, "File xuất chưa sẵn sàng")
+	}
+	if job.FilePath == "" || job.FileExpiresAt == nil || job.FileExpiresAt.Before(time.Now()) {
+		return fiber.NewError(fiber.StatusBadRequest, "File xuất đã hết hạn, vui lòng tạo lại")
+	}
+
+	token, err := common.GenerateRandToken(32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo liên kết tải xuống")
+	}
+
+	// The link never outlives the file
+	expiresAt := time.Now().Add(common.GetEnvDuration("EXPORT_LINK_TTL", 15*time.Minute))
+	if job.FileExpiresAt.Before(expiresAt) {
+		expiresAt = *job.FileExpiresAt
+	}
+
+	if err := common.DBConn.Model(job).Updates(map[string]interface{}{
+		"download_token_hash": common.HashToken(token),
+		"download_expires_at": expiresAt,
+	}).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo liên kết tải xuống")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", exportJobLink{
+		URL:       fmt.Sprintf("/api/jobs/%d/download?token=%s", job.ID, token),
+		ExpiresAt: expiresAt,
+	}))
+}
+
+// [GET] /api/jobs/:id/download
+func ExportJobDownload(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Liên kết tải xuống không hợp lệ hoặc đã hết hạn")
+	}
+
+	var job entity.ExportJob
+	if err := common.DBConn.First(&job, "id = ? AND status = ? AND download_token_hash = ? AND download_expires_at > ?",
+		c.Params("id"), entity.ExportJobSucceeded, common.HashToken(token), time.Now()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Liên kết tải xuống không hợp lệ hoặc đã hết hạn")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if _, err := os.Stat(job.FilePath); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "File xuất đã hết hạn, vui lòng tạo lại")
+	}
+
+	return c.Download(job.FilePath, job.FileName)
+}
+
+func wakeExportWorker() {
+	select {
+	case exportJobWake <- struct{}{}:
+	default:
+	}
+}
+
+// StartExportJobs queues again the jobs this instance was running before its restart and the stale jobs of other
+// instances, then starts the export workers, the heartbeat and the cleanup of expired files.
+func StartExportJobs() {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	exportInstanceId = common.GetEnvString("EXPORT_INSTANCE_ID", hostname)
+
+	if err := os.MkdirAll(exportDir(), 0755); err != nil {
+		log.Println("Error creating export folder:", err)
+	}
+
+	if _, err := requeueExportJobs(func(db *gorm.DB) *gorm.DB {
+		return db.Where("worker_id = ?", exportInstanceId)
+	}); err != nil {
+		log.Println("Error requeueing export jobs:", err)
+	}
+	if _, err := requeueExportJobs(staleExportJobs); err != nil {
+		log.Println("Error requeueing export jobs:", err)
+	}
+
+	for i := 0; i < common.GetEnvInt("EXPORT_WORKERS", 2); i++ {
+		go exportWorker()
+	}
+	go exportHeartbeat()
+	go exportCleanup()
+}
+
+// staleExportJobs selects the running jobs whose instance stopped beating.
+func staleExportJobs(db *gorm.DB) *gorm.DB {
+	return db.Where("heartbeat_at IS NULL OR heartbeat_at < ?", time.Now().Add(-exportJobStaleAfter))
+}
+
+// requeueExportJobs puts the running jobs the scope selects back in the queue, they start over from the beginning.
+func requeueExportJobs(scope func(db *gorm.DB) *gorm.DB) (int64, error) {
+	result := common.DBConn.Model(&entity.ExportJob{}).Where("status = ?", entity.ExportJobRunning).Scopes(scope).
+		Updates(map[string]interface{}{
+			"status":       entity.ExportJobQueued,
+			"done":         0,
+			"progress":     0,
+			"started_at":   nil,
+			"worker_id":    "",
+			"heartbeat_at": nil,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// exportHeartbeat keeps the jobs of this instance alive and requeues the jobs of the instances that stopped.
+func exportHeartbeat() {
+	ticker := time.NewTicker(exportJobHeartbeatInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := common.DBConn.Model(&entity.ExportJob{}).
+			Where("status = ? AND worker_id = ?", entity.ExportJobRunning, exportInstanceId).
+			Update("heartbeat_at", time.Now()).Error; err != nil {
+			log.Println("Error saving export job heartbeat:", err)
+		}
+
+		requeued, err := requeueExportJobs(staleExportJobs)
+		if err != nil {
+			log.Println("Error requeueing export jobs:", err)
+		}
+		if requeued > 0 {
+			wakeExportWorker()
+		}
+	}
+}
+
+func exportWorker() {
+	ticker := time.NewTicker(exportJobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			job, err := claimExportJob()
+			if err != nil {
+				log.Println("Error claiming export job:", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			runExportJob(job)
+		}
+
+		select {
+		case <-exportJobWake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimExportJob marks the oldest queued job as running on this instance. Locked rows are skipped, so each job goes
+// to one worker even with several instances of the API.
+func claimExportJob() (*entity.ExportJob, error) {
+	var job entity.ExportJob
+	err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", entity.ExportJobQueued).Order("id").First(&job).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		job.Status = entity.ExportJobRunning
+		job.StartedAt = &now
+		job.WorkerID = exportInstanceId
+		job.HeartbeatAt = &now
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":       job.Status,
+			"started_at":   now,
+			"worker_id":    job.WorkerID,
+			"heartbeat_at": now,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func buildExportJob(job *entity.ExportJob, progress exportProgress) (*excelize.File, error) {
+	if job.Kind == entity.ExportKindTranscripts {
+		return buildTranscriptExport(job.Params, progress)
+	}
+	return buildGradeExport("Grades", gradeExportFilter(job.Params), progress)
+}
+
+func exportJobFileName(job *entity.ExportJob) string {
+	target := job.Params.StudentID
+	switch job.Kind {
+	case entity.ExportKindGradesByDepartment:
+		target = strconv.FormatUint(uint64(job.Params.DepartmentID), 10)
+	case entity.ExportKindGradesBySubject:
+		target = job.Params.SubjectID
+	case entity.ExportKindGradesByClass:
+		target = job.Params.ClassID
+	case entity.ExportKindTranscripts:
+		if job.Params.ClassID != "" {
+			target = job.Params.ClassID
+		}
+	}
+	return fmt.Sprintf("%s_%s.xlsx", job.Kind, target)
+}
+
+func runExportJob(job *entity.ExportJob) {
+	// Every run writes its own file, a run that lost the job never removes the file of the next one
+	path := filepath.Join(exportDir(), fmt.Sprintf("%d_%d.xlsx", job.ID, job.StartedAt.UnixNano()))
+	// A job requeued as stale may run elsewhere by now, so only the instance that claimed it may save it
+	running := func() *gorm.DB {
+		return common.DBConn.Model(&entity.ExportJob{}).
+			Where("id = ? AND status = ? AND worker_id = ?", job.ID, entity.ExportJobRunning, exportInstanceId)
+	}
+
+	// Progress is saved only while the job is still running, so a cancel is noticed at the next batch
+	progress := func(done int, total int) error {
+		percent := 100
+		if total > 0 {
+			percent = done * 100 / total
+		}
+		result := running().Updates(map[string]interface{}{"done": done, "total": total, "progress": percent, "heartbeat_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errExportJobCancelled
+		}
+		return nil
+	}
+
+	f, err := buildExportJob(job, progress)
+	if err == nil {
+		err = f.SaveAs(path)
+		if closeErr := f.Close(); closeErr != nil {
+			fmt.Println(closeErr)
+		}
+	}
+
+	if err != nil {
+		_ = os.Remove(path)
+		if errors.Is(err, errExportJobCancelled) {
+			return
+		}
+
+		message := []rune(err.Error())
+		if len(message) > exportJobErrorMaxLength {
+			message = message[:exportJobErrorMaxLength]
+		}
+		if err := running().Updates(map[string]interface{}{
+			"status":      entity.ExportJobFailed,
+			"error":       string(message),
+			"finished_at": time.Now(),
+		}).Error; err != nil {
+			log.Println("Error saving export job:", err)
+		}
+		return
+	}
+
+	now := time.Now()
+	result := running().Updates(map[string]interface{}{
+		"status":          entity.ExportJobSucceeded,
+		"progress":        100,
+		"file_name":       exportJobFileName(job),
+		"file_path":       path,
+		"file_expires_at": now.Add(common.GetEnvDuration("EXPORT_RETENTION", 24*time.Hour)),
+		"finished_at":     now,
+	})
+	if result.Error != nil {
+		log.Println("Error saving export job:", result.Error)
+	}
+	// Cancelled while the file was being saved
+	if result.Error != nil || result.RowsAffected == 0 {
+		_ = os.Remove(path)
+	}
+}
+
+// exportCleanup removes the files of the jobs past their retention. The jobs are kept, without their file.
+func exportCleanup() {
+	ticker := time.NewTicker(exportJobCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		var jobs []entity.ExportJob
+		if err := common.DBConn.Select("id", "file_path").
+			Find(&jobs, "file_path <> '' AND file_expires_at < ?", time.Now()).Error; err != nil {
+			log.Println("Error loading expired export jobs:", err)
+		}
+
+		for _, job := range jobs {
+			if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+				log.Println("Error removing export file:", err)
+				continue
+			}
+			if err := common.DBConn.Model(&job).Updates(map[string]interface{}{
+				"file_path":           "",
+				"download_token_hash": "",
+				"download_expires_at": nil,
+			}).Error; err != nil {
+				log.Println("Error saving export job:", err)
+			}
+		}
+
+		<-ticker.C
+	}
+}
@@ -2,21 +2,123 @@ package controllers
 
 import (
 	"errors"
-	"fmt"
 	"github.com/gofiber/fiber/v2"
-	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
 	"qldiemsv/common"
 	"qldiemsv/models/entity"
 	"qldiemsv/models/req"
-	"sync"
 )
 
+// requestGradeComponents checks the component scores of a grade request against the components of the subject.
+// Requests without components give the process, midterm and final scores, for the subjects that have those components.
+func requestGradeComponents(subjectId string, components []req.GradeComponentScore, legacy []entity.GradeComponentScore) ([]entity.GradeComponentScore, error) {
+	var subject entity.Subject
+	if err := common.DBConn.Preload("Components", preloadAssessmentComponents).First(&subject, "id = ?", subjectId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy môn học")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return subjectGradeComponents(subject, components, legacy)
+}
+
+// subjectGradeComponents checks the component scores against the components of the loaded subject.
+func subjectGradeComponents(subject entity.Subject, components []req.GradeComponentScore, legacy []entity.GradeComponentScore) ([]entity.GradeComponentScore, error) {
+	codes := make(map[string]bool)
+	for _, component := range common.SubjectComponents(subject) {
+		codes[component.Code] = true
+	}
+
+	scores := make([]entity.GradeComponentScore, 0)
+	if len(components) == 0 {
+		for _, score := range legacy {
+			if codes[score.Code] {
+				scores = append(scores, score)
+			}
+		}
+		if len(scores) == 0 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Vui lòng nhập điểm theo các thành phần điểm của môn học")
+		}
+		return scores, nil
+	}
+
+	seen := make(map[string]bool, len(components))
+	for _, component := range components {
+		if !codes[component.Code] {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Môn học không có thành phần điểm "+component.Code)
+		}
+		if seen[component.Code] {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Thành phần điểm "+component.Code+" bị trùng")
+		}
+		seen[component.Code] = true
+
+		scores = append(scores, entity.GradeComponentScore{
+			Code:   component.Code,
+			Score:  component.Score,
+			Status: component.Status,
+		})
+	}
+
+	return scores, nil
+}
+
+// saveGradeComponents replaces the stored component scores of the grade.
+func saveGradeComponents(tx *gorm.DB, grade *entity.Grade) error {
+	if err := tx.Delete(&entity.GradeComponentScore{}, "grade_id = ?", grade.ID).Error; err != nil {
+		return err
+	}
+	if len(grade.Components) == 0 {
+		return nil
+	}
+	for i := range grade.Components {
+		grade.Components[i].GradeID = grade.ID
+	}
+	return tx.Create(&grade.Components).Error
+}
+
+// gradeAttemptsBefore counts, for each student, the grades of the subject in the terms before the term. Grades
+// recorded before terms existed come first.
+func gradeAttemptsBefore(subjectId string, studentsId []string, term entity.AcademicTerm) (map[string]int, error) {
+	var counts []struct {
+		StudentID string
+		Attempts  int
+	}
+	if err := common.DBConn.Model(&entity.Grade{}).Select("grades.student_id, COUNT(*) AS attempts").
+		Joins("LEFT JOIN academic_terms ON academic_terms.id = grades.term_id").
+		Where("grades.subject_id = ? AND grades.student_id IN ?", subjectId, studentsId).
+		Where("grades.term_id IS NULL OR academic_terms.year < ? OR (academic_terms.year = ? AND academic_terms.semester < ?)", term.Year, term.Year, term.Semester).
+		Group("grades.student_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	attempts := make(map[string]int, len(counts))
+	for _, count := range counts {
+		attempts[count.StudentID] = count.Attempts
+	}
+
+	return attempts, nil
+}
+
+// shiftLaterAttempts makes room for a grade entered after the grades of later terms, those become the next attempts.
+func shiftLaterAttempts(tx *gorm.DB, subjectId string, studentsId []string, term entity.AcademicTerm) error {
+	laterTerms := tx.Model(&entity.AcademicTerm{}).Select("id").
+		Where("year > ? OR (year = ? AND semester > ?)", term.Year, term.Year, term.Semester)
+
+	return tx.Model(&entity.Grade{}).
+		Where("subject_id = ? AND student_id IN ? AND term_id IN (?)", subjectId, studentsId, laterTerms).
+		UpdateColumn("attempt", gorm.Expr("attempt + 1")).Error
+}
+
 // [GET] /api/grades
 func GradeGetList(c *fiber.Ctx) error {
 	var grades []entity.Grade
 
-	if err := common.DBConn.Find(&grades).Error; err != nil {
+	if err := common.DBConn.Scopes(scopeSubjectDepartment(c, "subject_id"), scopeTerm(c)).Find(&grades).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := fillGradeResults(grades); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -35,6 +137,14 @@ func GradeGetById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkSubjectScope(c, grade.SubjectID); err != nil {
+		return err
+	}
+
+	if err := fillGradeResult(&grade); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", grade))
 }
 
@@ -50,13 +160,21 @@ func GradeGetAllByDepartmentId(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, department.ID); err != nil {
+		return err
+	}
+
 	var subjectsId []string
 	if err := common.DBConn.Model(&entity.Subject{}).Select("id").Where("department_id = ?", departmentId).Find(&subjectsId).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
 	var grades []entity.Grade
-	if err := common.DBConn.Where("subject_id IN ?", subjectsId).Find(&grades).Error; err != nil {
+	if err := common.DBConn.Where("subject_id IN ?", subjectsId).Scopes(scopeTerm(c)).Find(&grades).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := fillGradeResults(grades); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -75,220 +193,68 @@ func GradeExportExcelByDepartmentId(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
-	var subjectsId []string
-	if err := common.DBConn.Model(&entity.Subject{}).Select("id").Where("department_id = ?", departmentId).Find(&subjectsId).Error; err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
-	}
-
-	var grades []entity.Grade
-	if err := common.DBConn.Where("subject_id IN ?", subjectsId).Find(&grades).Error; err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
-	}
-
-	f := excelize.NewFile()
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
-
-	if err := f.SetSheetName("Sheet1", department.Name); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
-	}
-
-	if err := f.SetColWidth(department.Name, "A", "I", 20); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
-	}
-
-	var headers = []string{"Mã sinh viên", "Tên sinh viên", "Điểm quá trình", "Điểm giữa kỳ", "Điểm cuối kỳ", "Môn học", "Giảng viên dạy", "Ngày tạo", "Ngày cập nhật"}
-	for idx, header := range headers {
-		cell, err := excelize.CoordinatesToCellName(idx+1, 1)
-		if err != nil {
-			fmt.Println(err)
-			return c.JSON(common.NewResponse(fiber.StatusInternalServerError, "Lỗi khi tạo file excel", nil))
-		}
-		if err := f.SetCellValue(department.Name, cell, header); err != nil {
-			return err
-		}
+	if err := checkDepartmentScope(c, department.ID); err != nil {
+		return err
 	}
 
-	var wg sync.WaitGroup
-	chErr := make(chan error, len(grades))
-	wg.Add(len(grades))
-	for idx, grade := range grades {
-		go func(idx int, grade entity.Grade) {
-			defer wg.Done()
-			var student entity.Student
-			if err := common.DBConn.First(&student, "id = ?", grade.StudentID).Error; err != nil {
-				chErr <- err
-				return
-			}
-
-			var subject entity.Subject
-			if err := common.DBConn.First(&subject, "id = ?", grade.SubjectID).Error; err != nil {
-				chErr <- err
-				return
-			}
-
-			var instructor entity.Instructor
-			if err := common.DBConn.First(&instructor, "id = ?", grade.ByInstructorID).Error; err != nil {
-				chErr <- err
-				return
-			}
-
-			data := []string{
-				student.ID,
-				student.FirstName + " " + student.LastName,
-				fmt.Sprintf("%.2f", grade.ProcessScore),
-				fmt.Sprintf("%.2f", grade.MidtermScore),
-				fmt.Sprintf("%.2f", grade.FinalScore),
-				subject.Name,
-				instructor.FirstName + " " + instructor.LastName,
-				grade.CreatedAt.Format("2006-01-02 15:04:05"),
-				grade.UpdatedAt.Format("2006-01-02 15:04:05"),
-			}
-			for i, cell := range data {
-				cellName, err := excelize.CoordinatesToCellName(i+1, idx+2)
-				if err != nil {
-					chErr <- err
-					return
-				}
-
-				if err := f.SetCellValue(department.Name, cellName, cell); err != nil {
-					chErr <- err
-					return
-				}
-			}
-		}(idx, grade)
-	}
-
-	go func() {
-		wg.Wait()
-		close(chErr)
-	}()
-
-	for err := range chErr {
-		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
-	}
+	filter := requestGradeExportFilter(c)
+	filter.DepartmentID = department.ID
 
-	if err := f.SaveAs("static/ByDepartmentList.xlsx"); err != nil {
-		fmt.Println(err)
+	f, err := buildGradeExport(department.Name, filter, nil)
+	if err != nil {
+		return err
 	}
 
-	return c.Download("static/ByDepartmentList.xlsx")
-
+	return sendExcel(c, f, "ByDepartmentList.xlsx")
 }
 
+// [GET] /api/grades/export
 func GradeExportExcelList(c *fiber.Ctx) error {
-	var grades []entity.Grade
-	if err := common.DBConn.Find(&grades).Error; err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	f, err := buildGradeExport("Grades", requestGradeExportFilter(c), nil)
+	if err != nil {
+		return err
 	}
 
-	f := excelize.NewFile()
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Println(err)
-		}
-	}()
+	return sendExcel(c, f, "GradeList.xlsx")
+}
 
-	if err := f.SetSheetName("Sheet1", "Grades"); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
-	}
+// [POST] /api/grades
+func GradeCreate(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.GradeCreate](c)
 
-	if err := f.SetColWidth("Grades", "A", "I", 20); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	var headers = []string{"Mã sinh viên", "Tên sinh viên", "Điểm quá trình", "Điểm giữa kỳ", "Điểm cuối kỳ", "Môn học", "Giảng viên dạy", "Ngày tạo", "Ngày cập nhật"}
-	for idx, header := range headers {
-		cell, err := excelize.CoordinatesToCellName(idx+1, 1)
-		if err != nil {
-			fmt.Println(err)
-			return c.JSON(common.NewResponse(fiber.StatusInternalServerError, "Lỗi khi tạo file excel", nil))
-		}
-		if err := f.SetCellValue("Grades", cell, header); err != nil {
-			return err
-		}
+	if err := checkSubjectScope(c, bodyData.SubjectID); err != nil {
+		return err
 	}
 
-	var wg sync.WaitGroup
-	chErr := make(chan error, len(grades))
-
-	wg.Add(len(grades))
-	for idx, grade := range grades {
-		go func(idx int, grade entity.Grade) {
-			defer wg.Done()
-			var student entity.Student
-			if err := common.DBConn.First(&student, "id = ?", grade.StudentID).Error; err != nil {
-				chErr <- err
-				return
-			}
-
-			var subject entity.Subject
-			if err := common.DBConn.First(&subject, "id = ?", grade.SubjectID).Error; err != nil {
-				chErr <- err
-				return
-			}
-
-			var instructor entity.Instructor
-			if err := common.DBConn.First(&instructor, "id = ?", grade.ByInstructorID).Error; err != nil {
-				chErr <- err
-				return
-			}
-
-			data := []string{
-				student.ID,
-				student.FirstName + " " + student.LastName,
-				fmt.Sprintf("%.2f", grade.ProcessScore),
-				fmt.Sprintf("%.2f", grade.MidtermScore),
-				fmt.Sprintf("%.2f", grade.FinalScore),
-				subject.Name,
-				instructor.FirstName + " " + instructor.LastName,
-				grade.CreatedAt.Format("2006-01-02 15:04:05"),
-				grade.UpdatedAt.Format("2006-01-02 15:04:05"),
-			}
-			for i, cell := range data {
-				cellName, err := excelize.CoordinatesToCellName(i+1, idx+2)
-				if err != nil {
-					chErr <- err
-					return
-				}
-
-				if err := f.SetCellValue("Grades", cellName, cell); err != nil {
-					chErr <- err
-					return
-				}
-			}
-		}(idx, grade)
+	// Accounts linked to an instructor always grade under their own name
+	instructorId, err := linkedInstructorId(c)
+	if err != nil {
+		return err
 	}
 
-	go func() {
-		wg.Wait()
-		close(chErr)
-	}()
-
-	for err := range chErr {
-		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+	if instructorId != "" {
+		bodyData.ByInstructorID = instructorId
 	}
 
-	if err := f.SaveAs("static/GradeList.xlsx"); err != nil {
-		fmt.Println(err)
+	if bodyData.ByInstructorID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "ByInstructorID không được để trống")
 	}
 
-	return c.Download("static/GradeList.xlsx")
-}
-
-// [POST] /api/grades
-func GradeCreate(c *fiber.Ctx) error {
-	bodyData, err := common.Validator[req.GradeCreate](c)
+	term, err := openTerm(bodyData.TermID)
+	if err != nil {
+		return err
+	}
 
-	if err != nil || bodyData == nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	if err := checkGradeEditable(bodyData.SubjectID, &term.ID); err != nil {
+		return err
 	}
 
 	var registration entity.StudentRegistration
-	if err := common.DBConn.First(&registration, "subject_id = ? and student_id = ?", bodyData.SubjectID, bodyData.StudentID).Error; err != nil {
+	if err := common.DBConn.First(&registration, "subject_id = ? and student_id = ? and term_id = ?", bodyData.SubjectID, bodyData.StudentID, term.ID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Sinh viên chưa đăng ký môn học này")
 		}
@@ -296,37 +262,70 @@ func GradeCreate(c *fiber.Ctx) error {
 	}
 
 	var assignment entity.InstructorAssignment
-	if err := common.DBConn.First(&assignment, "subject_id = ? and instructor_id = ?", bodyData.SubjectID, bodyData.ByInstructorID).Error; err != nil {
+	if err := common.DBConn.First(&assignment, "subject_id = ? and instructor_id = ? and term_id = ?", bodyData.SubjectID, bodyData.ByInstructorID, term.ID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Giảng viên không dạy môn học này")
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	// Each registration is one attempt at the subject, a retake needs a new registration in a later term
 	var grade entity.Grade
-	if err := common.DBConn.First(&grade, "subject_id = ? and student_id = ?", bodyData.SubjectID, bodyData.StudentID).Error; err != nil {
+	if err := common.DBConn.Select("id").First(&grade, "registration_id = ?", registration.ID).Error; err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return fiber.NewError(fiber.StatusBadRequest, "Lỗi khi truy vấn cơ sở dữ liệu")
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 		}
 	}
 
 	if grade.ID != 0 {
-		return fiber.NewError(fiber.StatusBadRequest, "Sinh viên đã có điểm môn học này")
+		return fiber.NewError(fiber.StatusBadRequest, "Sinh viên đã có điểm cho lần đăng ký môn học này")
+	}
+
+	// Attempts follow the order of the terms, not the order the grades are entered in
+	attemptsBefore, err := gradeAttemptsBefore(bodyData.SubjectID, []string{bodyData.StudentID}, *term)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	scores, err := requestGradeComponents(bodyData.SubjectID, bodyData.Components, common.LegacyComponentScores(
+		bodyData.ProcessScore, bodyData.MidtermScore, bodyData.FinalScore,
+		bodyData.ProcessStatus, bodyData.MidtermStatus, bodyData.FinalStatus))
+	if err != nil {
+		return err
 	}
 
 	newGrade := entity.Grade{
-		ProcessScore:   bodyData.ProcessScore,
-		MidtermScore:   bodyData.MidtermScore,
-		FinalScore:     bodyData.FinalScore,
 		SubjectID:      bodyData.SubjectID,
 		StudentID:      bodyData.StudentID,
 		ByInstructorID: bodyData.ByInstructorID,
+		TermID:         &term.ID,
+		RegistrationID: &registration.ID,
+		Attempt:        attemptsBefore[bodyData.StudentID] + 1,
 	}
+	common.SetGradeComponents(&newGrade, scores)
 
-	if err := common.DBConn.Create(&newGrade).Error; err != nil {
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := ensureGradeSheet(tx, newGrade.SubjectID, term.ID); err != nil {
+			return err
+		}
+		if err := shiftLaterAttempts(tx, newGrade.SubjectID, []string{newGrade.StudentID}, *term); err != nil {
+			return err
+		}
+		if err := tx.Omit("Components").Create(&newGrade).Error; err != nil {
+			return err
+		}
+		if err := saveGradeComponents(tx, &newGrade); err != nil {
+			return err
+		}
+		return recordGradeHistory(tx, c, entity.GradeHistoryCreate, nil, &newGrade, "")
+	}); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo điểm")
 	}
 
+	if err := fillGradeResult(&newGrade); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", newGrade))
 }
 
@@ -341,21 +340,51 @@ func GradeUpdateById(c *fiber.Ctx) error {
 
 	var grade entity.Grade
 
-	if err := common.DBConn.First(&grade, "id = ?", gradeId).Error; err != nil {
+	if err := common.DBConn.Preload("Components").First(&grade, "id = ?", gradeId).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy bảng điểm")
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
-	grade.ProcessScore = bodyData.ProcessScore
-	grade.MidtermScore = bodyData.MidtermScore
-	grade.FinalScore = bodyData.FinalScore
+	if err := checkSubjectScope(c, grade.SubjectID); err != nil {
+		return err
+	}
+
+	if err := checkInstructorAssignment(c, grade.SubjectID, grade.TermID); err != nil {
+		return err
+	}
+
+	if err := checkGradeEditable(grade.SubjectID, grade.TermID); err != nil {
+		return err
+	}
 
-	if err := common.DBConn.Save(&grade).Error; err != nil {
+	scores, err := requestGradeComponents(grade.SubjectID, bodyData.Components, common.LegacyComponentScores(
+		bodyData.ProcessScore, bodyData.MidtermScore, bodyData.FinalScore,
+		bodyData.ProcessStatus, bodyData.MidtermStatus, bodyData.FinalStatus))
+	if err != nil {
+		return err
+	}
+
+	before := grade
+	common.SetGradeComponents(&grade, scores)
+
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Components").Save(&grade).Error; err != nil {
+			return err
+		}
+		if err := saveGradeComponents(tx, &grade); err != nil {
+			return err
+		}
+		return recordGradeHistory(tx, c, entity.GradeHistoryUpdate, &before, &grade, bodyData.Reason)
+	}); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật điểm")
 	}
 
+	if err := fillGradeResult(&grade); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", grade))
 }
 
@@ -364,16 +393,40 @@ func GradeDeleteById(c *fiber.Ctx) error {
 	gradeId := c.Params("id")
 
 	var grade entity.Grade
-	if err := common.DBConn.First(&grade, "id = ?", gradeId).Error; err != nil {
+	if err := common.DBConn.Preload("Components").First(&grade, "id = ?", gradeId).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy bảng điểm")
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
-	if err := common.DBConn.Delete(&grade).Error; err != nil {
+	if err := checkSubjectScope(c, grade.SubjectID); err != nil {
+		return err
+	}
+
+	if err := checkInstructorAssignment(c, grade.SubjectID, grade.TermID); err != nil {
+		return err
+	}
+
+	if err := checkGradeEditable(grade.SubjectID, grade.TermID); err != nil {
+		return err
+	}
+
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&entity.GradeComponentScore{}, "grade_id = ?", grade.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&grade).Error; err != nil {
+			return err
+		}
+		return recordGradeHistory(tx, c, entity.GradeHistoryDelete, &grade, nil, c.Query("reason"))
+	}); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa bảng điểm")
 	}
 
+	if err := fillGradeResult(&grade); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", grade))
 }
@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"time"
+)
+
+func findGradeAmendment(c *fiber.Ctx) (*entity.GradeAmendment, *entity.Grade, error) {
+	amendmentId := c.Params("id")
+	var amendment entity.GradeAmendment
+
+	if err := common.DBConn.First(&amendment, "id = ?", amendmentId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy yêu cầu điều chỉnh điểm")
+		}
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	var grade entity.Grade
+	if err := common.DBConn.Preload("Components").First(&grade, "id = ?", amendment.GradeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy bảng điểm")
+		}
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := checkSubjectScope(c, grade.SubjectID); err != nil {
+		return nil, nil, err
+	}
+
+	return &amendment, &grade, nil
+}
+
+// amendmentReviewNote reads the optional note of an approval or rejection, the body may be left out.
+func amendmentReviewNote(c *fiber.Ctx) (string, error) {
+	if len(c.Body()) == 0 {
+		return "", nil
+	}
+
+	bodyData, err := common.Validator[req.GradeAmendmentReview](c)
+	if err != nil || bodyData == nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	return bodyData.Note, nil
+}
+
+// [GET] /api/grade-amendments
+func GradeAmendmentGetAll(c *fiber.Ctx) error {
+	var amendments []entity.GradeAmendment
+
+	query := common.DBConn.Where("grade_id IN (?)", common.DBConn.Model(&entity.Grade{}).Select("id").Scopes(scopeSubjectDepartment(c, "subject_id"))).
+		Order("created_at desc")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&amendments).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", amendments))
+}
+
+// [GET] /api/grades/:id/amendments
+func GradeAmendmentGetAllByGradeId(c *fiber.Ctx) error {
+	gradeId := c.Params("id")
+	var grade entity.Grade
+
+	if err := common.DBConn.First(&grade, "id = ?", gradeId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy bảng điểm")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := checkSubjectScope(c, grade.SubjectID); err != nil {
+		return err
+	}
+
+	var amendments []entity.GradeAmendment
+	if err := common.DBConn.Order("created_at desc").Find(&amendments, "grade_id = ?", grade.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", amendments))
+}
+
+// [POST] /api/grades/:id/amendments
+func GradeAmendmentCreate(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.GradeAmendmentCreate](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	userId, err := requestUserId(c)
+	if err != nil {
+		return err
+	}
+
+	gradeId := c.Params("id")
+	var grade entity.Grade
+
+	if err := common.DBConn.Preload("Components").First(&grade, "id = ?", gradeId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy bảng điểm")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := checkSubjectScope(c, grade.SubjectID); err != nil {
+		return err
+	}
+
+	if err := checkInstructorAssignment(c, grade.SubjectID, grade.TermID); err != nil {
+		return err
+	}
+
+	status, err := gradeSheetStatus(common.DBConn, grade.SubjectID, grade.TermID)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if status != entity.GradeSheetStatusPublished {
+		return fiber.NewError(fiber.StatusBadRequest, "Bảng điểm chưa khóa, không cần yêu cầu điều chỉnh điểm")
+	}
+
+	var pending entity.GradeAmendment
+	if err := common.DBConn.Select("id").First(&pending, "grade_id = ? AND status = ?", grade.ID, entity.AmendmentStatusPending).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+	}
+
+	if pending.ID != 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Điểm này đang có yêu cầu điều chỉnh chờ duyệt")
+	}
+
+	components, err := requestGradeComponents(grade.SubjectID, bodyData.Components, common.LegacyComponentScores(
+		bodyData.ProcessScore, bodyData.MidtermScore, bodyData.FinalScore,
+		bodyData.ProcessStatus, bodyData.MidtermStatus, bodyData.FinalStatus))
+	if err != nil {
+		return err
+	}
+
+	// The scores are stored as they will be applied
+	scores := entity.Grade{}
+	common.SetGradeComponents(&scores, components)
+
+	newAmendment := entity.GradeAmendment{
+		GradeID:          grade.ID,
+		OldProcessScore:  grade.ProcessScore,
+		OldMidtermScore:  grade.MidtermScore,
+		OldFinalScore:    grade.FinalScore,
+		ProcessScore:     scores.ProcessScore,
+		MidtermScore:     scores.MidtermScore,
+		FinalScore:       scores.FinalScore,
+		OldProcessStatus: grade.ProcessStatus,
+		OldMidtermStatus: grade.MidtermStatus,
+		OldFinalStatus:   grade.FinalStatus,
+		ProcessStatus:    scores.ProcessStatus,
+		MidtermStatus:    scores.MidtermStatus,
+		FinalStatus:      scores.FinalStatus,
+		OldComponents:    common.GradeComponentScores(grade),
+		Components:       scores.Components,
+		Reason:           bodyData.Reason,
+		Status:           entity.AmendmentStatusPending,
+		RequestedByID:    userId,
+	}
+
+	if err := common.DBConn.Create(&newAmendment).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo yêu cầu điều chỉnh điểm")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", newAmendment))
+}
+
+// [POST] /api/grade-amendments/:id/approve
+func GradeAmendmentApproveById(c *fiber.Ctx) error {
+	note, err := amendmentReviewNote(c)
+	if err != nil {
+		return err
+	}
+
+	userId, err := requestUserId(c)
+	if err != nil {
+		return err
+	}
+
+	amendment, grade, err := findGradeAmendment(c)
+	if err != nil {
+		return err
+	}
+
+	if amendment.Status != entity.AmendmentStatusPending {
+		return fiber.NewError(fiber.StatusBadRequest, "Yêu cầu điều chỉnh điểm đã được xử lý")
+	}
+
+	if amendment.RequestedByID == userId {
+		return fiber.NewError(fiber.StatusForbidden, "Người duyệt phải khác người yêu cầu điều chỉnh điểm")
+	}
+
+	now := time.Now()
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.GradeAmendment{}).
+			Where("id = ? AND status = ?", amendment.ID, entity.AmendmentStatusPending).
+			Updates(map[string]any{
+				"status":         entity.AmendmentStatusApproved,
+				"reviewed_by_id": userId,
+				"reviewed_at":    now,
+				"review_note":    note,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fiber.NewError(fiber.StatusConflict, "Yêu cầu điều chỉnh điểm đã được xử lý")
+		}
+
+		before := *grade
+		components := amendment.Components
+		// Amendments requested before components were configurable only have the process, midterm and final scores
+		if len(components) == 0 {
+			components = common.LegacyComponentScores(
+				amendment.ProcessScore, amendment.MidtermScore, amendment.FinalScore,
+				amendment.ProcessStatus, amendment.MidtermStatus, amendment.FinalStatus)
+		}
+		common.SetGradeComponents(grade, components)
+		// A locked result is computed again from the amended scores and locked anew
+		if grade.ResultLockedAt != nil {
+			grade.ResultLockedAt = nil
+			if err := fillGradeResult(grade); err != nil {
+				return err
+			}
+			grade.ResultLockedAt = &now
+		}
+		if err := tx.Omit("Components").Save(grade).Error; err != nil {
+			return err
+		}
+		if err := saveGradeComponents(tx, grade); err != nil {
+			return err
+		}
+		return recordGradeHistory(tx, c, entity.GradeHistoryAmend, &before, grade, amendment.Reason)
+	}); err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return err
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật điểm")
+	}
+
+	amendment.Status = entity.AmendmentStatusApproved
+	amendment.ReviewedByID = &userId
+	amendment.ReviewedAt = &now
+	amendment.ReviewNote = note
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", amendment))
+}
+
+// [POST] /api/grade-amendments/:id/reject
+func GradeAmendmentRejectById(c *fiber.Ctx) error {
+	note, err := amendmentReviewNote(c)
+	if err != nil {
+		return err
+	}
+
+	userId, err := requestUserId(c)
+	if err != nil {
+		return err
+	}
+
+	amendment, _, err := findGradeAmendment(c)
+	if err != nil {
+		return err
+	}
+
+	if amendment.Status != entity.AmendmentStatusPending {
+		return fiber.NewError(fiber.StatusBadRequest, "Yêu cầu điều chỉnh điểm đã được xử lý")
+	}
+
+	now := time.Now()
+	result := common.DBConn.Model(&entity.GradeAmendment{}).
+		Where("id = ? AND status = ?", amendment.ID, entity.AmendmentStatusPending).
+		Updates(map[string]any{
+			"status":         entity.AmendmentStatusRejected,
+			"reviewed_by_id": userId,
+			"reviewed_at":    now,
+			"review_note":    note,
+		})
+	if result.Error != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật yêu cầu điều chỉnh điểm")
+	}
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusConflict, "Yêu cầu điều chỉnh điểm đã được xử lý")
+	}
+
+	amendment.Status = entity.AmendmentStatusRejected
+	amendment.ReviewedByID = &userId
+	amendment.ReviewedAt = &now
+	amendment.ReviewNote = note
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", amendment))
+}
@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+)
+
+const (
+	gradeBatchMaxRows = 1000
+
+	gradeBatchActionCreate = "create"
+	gradeBatchActionUpdate = "update"
+)
+
+type gradeBatchResult struct {
+	Row       int           `json:"row"`
+	StudentID string        `json:"student_id"`
+	Action    string        `json:"action,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Grade     *entity.Grade `json:"grade,omitempty"`
+}
+
+type gradeBatchUpdate struct {
+	before entity.Grade
+	grade  *entity.Grade
+}
+
+// gradeBatch is a roster of grades checked against the subject offering, ready to be saved as a whole.
+type gradeBatch struct {
+	subjectId string
+	term      *entity.AcademicTerm
+	reason    string
+	creates   []*entity.Grade
+	updates   []gradeBatchUpdate
+	results   []gradeBatchResult
+	failed    int
+}
+
+// prepareGradeBatch checks the batch and every row of it. Errors of the batch as a whole are returned, errors of
+// a row are reported in its result and counted in failed.
+func prepareGradeBatch(c *fiber.Ctx, bodyData *req.GradeBatch) (*gradeBatch, error) {
+	if len(bodyData.Rows) == 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Danh sách điểm không được để trống")
+	}
+	if len(bodyData.Rows) > gradeBatchMaxRows {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Mỗi lần chỉ được nhập tối đa %d điểm", gradeBatchMaxRows))
+	}
+
+	if err := checkSubjectScope(c, bodyData.SubjectID); err != nil {
+		return nil, err
+	}
+
+	// Accounts linked to an instructor always grade under their own name
+	instructorId, err := linkedInstructorId(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if instructorId != "" {
+		bodyData.ByInstructorID = instructorId
+	}
+
+	if bodyData.ByInstructorID == "" {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "ByInstructorID không được để trống")
+	}
+
+	term, err := openTerm(bodyData.TermID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkGradeEditable(bodyData.SubjectID, &term.ID); err != nil {
+		return nil, err
+	}
+
+	var subject entity.Subject
+	if err := common.DBConn.Preload("Components", preloadAssessmentComponents).First(&subject, "id = ?", bodyData.SubjectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy môn học")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	var assignment entity.InstructorAssignment
+	if err := common.DBConn.First(&assignment, "subject_id = ? and instructor_id = ? and term_id = ?", bodyData.SubjectID, bodyData.ByInstructorID, term.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Giảng viên không dạy môn học này")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	studentsId := make([]string, 0, len(bodyData.Rows))
+	for _, row := range bodyData.Rows {
+		studentsId = append(studentsId, row.StudentID)
+	}
+
+	var registrations []entity.StudentRegistration
+	if err := common.DBConn.Find(&registrations, "subject_id = ? and term_id = ? and student_id IN ?", bodyData.SubjectID, term.ID, studentsId).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	registrationsByStudent := make(map[string]entity.StudentRegistration, len(registrations))
+	registrationsId := make([]uint, 0, len(registrations))
+	for _, registration := range registrations {
+		registrationsByStudent[registration.StudentID] = registration
+		registrationsId = append(registrationsId, registration.ID)
+	}
+
+	// A student who already has the grade of this registration gets it updated
+	gradesByRegistration := make(map[uint]*entity.Grade)
+	if len(registrationsId) > 0 {
+		var grades []entity.Grade
+		if err := common.DBConn.Preload("Components").Find(&grades, "registration_id IN ?", registrationsId).Error; err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+		for i := range grades {
+			gradesByRegistration[*grades[i].RegistrationID] = &grades[i]
+		}
+	}
+
+	// Attempts follow the order of the terms, not the order the grades are entered in
+	attemptsBefore, err := gradeAttemptsBefore(bodyData.SubjectID, studentsId, *term)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	batch := &gradeBatch{
+		subjectId: subject.ID,
+		term:      term,
+		reason:    bodyData.Reason,
+		results:   make([]gradeBatchResult, 0, len(bodyData.Rows)),
+	}
+	seen := make(map[string]bool, len(bodyData.Rows))
+
+	for idx, row := range bodyData.Rows {
+		result := gradeBatchResult{Row: idx + 1, StudentID: row.StudentID}
+		rowError := func(message string) {
+			result.Error = message
+			batch.failed++
+			batch.results = append(batch.results, result)
+		}
+
+		if err := common.ValidateStruct(row); err != nil {
+			rowError(err.Error())
+			continue
+		}
+
+		if seen[row.StudentID] {
+			rowError("Sinh viên bị trùng trong danh sách")
+			continue
+		}
+		seen[row.StudentID] = true
+
+		registration, ok := registrationsByStudent[row.StudentID]
+		if !ok {
+			rowError("Sinh viên chưa đăng ký môn học này")
+			continue
+		}
+
+		scores, err := subjectGradeComponents(subject, row.Components, common.LegacyComponentScores(
+			row.ProcessScore, row.MidtermScore, row.FinalScore,
+			row.ProcessStatus, row.MidtermStatus, row.FinalStatus))
+		if err != nil {
+			rowError(err.Error())
+			continue
+		}
+
+		if grade, ok := gradesByRegistration[registration.ID]; ok {
+			before := *grade
+			common.SetGradeComponents(grade, scores)
+			batch.updates = append(batch.updates, gradeBatchUpdate{before: before, grade: grade})
+
+			result.Action = gradeBatchActionUpdate
+			result.Grade = grade
+		} else {
+			grade := &entity.Grade{
+				SubjectID:      subject.ID,
+				StudentID:      row.StudentID,
+				ByInstructorID: bodyData.ByInstructorID,
+				TermID:         &term.ID,
+				RegistrationID: &registration.ID,
+				Attempt:        attemptsBefore[row.StudentID] + 1,
+			}
+			common.SetGradeComponents(grade, scores)
+			batch.creates = append(batch.creates, grade)
+
+			result.Action = gradeBatchActionCreate
+			result.Grade = grade
+		}
+		batch.results = append(batch.results, result)
+	}
+
+	return batch, nil
+}
+
+// save writes the whole batch in one transaction, nothing is saved if any row of it failed.
+func (batch *gradeBatch) save(c *fiber.Ctx) error {
+	if batch.failed > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Danh sách điểm có dòng không hợp lệ")
+	}
+
+	return common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := ensureGradeSheet(tx, batch.subjectId, batch.term.ID); err != nil {
+			return err
+		}
+
+		if len(batch.creates) > 0 {
+			createdStudentsId := make([]string, 0, len(batch.creates))
+			for _, grade := range batch.creates {
+				createdStudentsId = append(createdStudentsId, grade.StudentID)
+			}
+			if err := shiftLaterAttempts(tx, batch.subjectId, createdStudentsId, *batch.term); err != nil {
+				return err
+			}
+			if err := tx.Omit("Components").Create(batch.creates).Error; err != nil {
+				return err
+			}
+		}
+
+		updatesId := make([]uint, 0, len(batch.updates))
+		for _, update := range batch.updates {
+			if err := tx.Omit("Components").Save(update.grade).Error; err != nil {
+				return err
+			}
+			updatesId = append(updatesId, update.grade.ID)
+		}
+		if len(updatesId) > 0 {
+			if err := tx.Delete(&entity.GradeComponentScore{}, "grade_id IN ?", updatesId).Error; err != nil {
+				return err
+			}
+		}
+
+		scores := make([]*entity.GradeComponentScore, 0)
+		histories := make([]entity.GradeHistory, 0, len(batch.creates)+len(batch.updates))
+		for _, grade := range batch.creates {
+			histories = append(histories, newGradeHistory(c, entity.GradeHistoryCreate, nil, grade, ""))
+		}
+		for _, update := range batch.updates {
+			histories = append(histories, newGradeHistory(c, entity.GradeHistoryUpdate, &update.before, update.grade, batch.reason))
+		}
+		for _, result := range batch.results {
+			for i := range result.Grade.Components {
+				result.Grade.Components[i].GradeID = result.Grade.ID
+				scores = append(scores, &result.Grade.Components[i])
+			}
+		}
+
+		if len(scores) > 0 {
+			if err := tx.Create(scores).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&histories).Error
+	})
+}
+
+// [POST] /api/grades/batch
+func GradeBatchSave(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.GradeBatch](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	batch, err := prepareGradeBatch(c, bodyData)
+	if err != nil {
+		return err
+	}
+
+	if batch.failed > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(fiber.StatusBadRequest,
+			fmt.Sprintf("Có %d dòng không hợp lệ, chưa có điểm nào được lưu", batch.failed), batch.results))
+	}
+
+	if err := batch.save(c); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi lưu điểm")
+	}
+
+	grades := make([]*entity.Grade, 0, len(batch.results))
+	for _, result := range batch.results {
+		grades = append(grades, result.Grade)
+	}
+	if err := fillGradeResultRefs(grades); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", batch.results))
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+)
+
+// Grades are read and written a batch at a time, so an export holds one batch in memory whatever its size
+const gradeExportBatchSize = 500
+
+var gradeExportHeaders = []interface{}{"Mã sinh viên", "Tên sinh viên", "Điểm thành phần", "Điểm tổng kết", "Điểm hệ 4", "Điểm chữ", "Kết quả", "Môn học", "Giảng viên dạy", "Ngày tạo", "Ngày cập nhật"}
+
+// gradeExportFilter selects the grades of an export, it is stored as the params of an export job.
+type gradeExportFilter entity.ExportJobParams
+
+// gradeExportRow is a grade joined with the names shown next to it.
+type gradeExportRow struct {
+	entity.Grade
+	StudentFirstName    string
+	StudentLastName     string
+	SubjectName         string
+	InstructorFirstName string
+	InstructorLastName  string
+}
+
+// requestGradeExportFilter reads the term and the department scope of the request.
+func requestGradeExportFilter(c *fiber.Ctx) gradeExportFilter {
+	filter := gradeExportFilter{}
+	if termId := c.QueryInt("term_id"); termId > 0 {
+		filter.TermID = uint(termId)
+	}
+	filter.DepartmentIds, filter.Scoped = departmentScope(c)
+	return filter
+}
+
+func (filter gradeExportFilter) query() *gorm.DB {
+	query := common.DBConn.Model(&entity.Grade{}).
+		Joins("JOIN students ON students.id = grades.student_id").
+		Joins("JOIN subjects ON subjects.id = grades.subject_id").
+		Joins("LEFT JOIN instructors ON instructors.id = grades.by_instructor_id")
+
+	if filter.DepartmentID != 0 {
+		query = query.Where("subjects.department_id = ?", filter.DepartmentID)
+	}
+	if filter.ClassID != "" {
+		query = query.Where("students.class_id = ?", filter.ClassID)
+	}
+	if filter.SubjectID != "" {
+		query = query.Where("grades.subject_id = ?", filter.SubjectID)
+	}
+	if filter.StudentID != "" {
+		query = query.Where("grades.student_id = ?", filter.StudentID)
+	}
+	if filter.Scoped {
+		if len(filter.DepartmentIds) == 0 {
+			return query.Where("1 = 0")
+		}
+		query = query.Where("subjects.department_id IN ?", filter.DepartmentIds)
+	}
+	if filter.TermID != 0 {
+		query = query.Where("grades.term_id = ?", filter.TermID)
+	}
+
+	return query
+}
+
+// gradeExporter computes the grades of an export, keeping the subjects and grading scales it has already loaded.
+type gradeExporter struct {
+	subjects map[string]entity.Subject
+	scales   map[string]entity.GradingScale
+}
+
+func (exporter *gradeExporter) loadSubjects(rows []gradeExportRow) error {
+	subjectsId := make([]string, 0)
+	for _, row := range rows {
+		if _, ok := exporter.subjects[row.SubjectID]; !ok {
+			subjectsId = append(subjectsId, row.SubjectID)
+		}
+	}
+	if len(subjectsId) == 0 {
+		return nil
+	}
+
+	var subjects []entity.Subject
+	if err := common.DBConn.Preload("Components", preloadAssessmentComponents).Find(&subjects, "id IN ?", subjectsId).Error; err != nil {
+		return err
+	}
+
+	scales, err := subjectGradingScales(subjects)
+	if err != nil {
+		return err
+	}
+
+	for _, subject := range subjects {
+		exporter.subjects[subject.ID] = subject
+		exporter.scales[subject.ID] = scales[subject.ID]
+	}
+
+	return nil
+}
+
+// exportProgress is told how many of the total rows are written after every batch, an error stops the export.
+type exportProgress func(done int, total int) error
+
+// buildGradeExport writes the grades of the filter to a new file through a stream writer. The caller closes the file.
+func buildGradeExport(sheet string, filter gradeExportFilter, progress exportProgress) (*excelize.File, error) {
+	f := excelize.NewFile()
+	stop := func(err error) (*excelize.File, error) {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+		return nil, err
+	}
+	fail := func(err error) (*excelize.File, error) {
+		return stop(fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err)))
+	}
+
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return fail(err)
+	}
+
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return fail(err)
+	}
+
+	if err := sw.SetColWidth(1, len(gradeExportHeaders), 20); err != nil {
+		return fail(err)
+	}
+
+	if err := sw.SetRow("A1", gradeExportHeaders); err != nil {
+		return fail(err)
+	}
+
+	var total int64
+	if progress != nil {
+		if err := filter.query().Count(&total).Error; err != nil {
+			return fail(err)
+		}
+		if err := progress(0, int(total)); err != nil {
+			return stop(err)
+		}
+	}
+
+	exporter := gradeExporter{subjects: make(map[string]entity.Subject), scales: make(map[string]entity.GradingScale)}
+	line := 2
+	var lastId uint
+	for {
+		var rows []gradeExportRow
+		if err := filter.query().Select("grades.*, students.first_name AS student_first_name, students.last_name AS student_last_name, "+
+			"subjects.name AS subject_name, instructors.first_name AS instructor_first_name, instructors.last_name AS instructor_last_name").
+			Where("grades.id > ?", lastId).Order("grades.id").Limit(gradeExportBatchSize).Scan(&rows).Error; err != nil {
+			return stop(fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu"))
+		}
+		if len(rows) == 0 {
+			break
+		}
+		lastId = rows[len(rows)-1].ID
+
+		refs := make([]*entity.Grade, 0, len(rows))
+		for i := range rows {
+			refs = append(refs, &rows[i].Grade)
+		}
+		if err := loadGradeComponents(refs); err != nil {
+			return fail(err)
+		}
+		if err := exporter.loadSubjects(rows); err != nil {
+			return fail(err)
+		}
+
+		for _, row := range rows {
+			subject := exporter.subjects[row.SubjectID]
+			common.ApplyGradeResult(&row.Grade, subject, exporter.scales[row.SubjectID])
+
+			cell, err := excelize.CoordinatesToCellName(1, line)
+			if err != nil {
+				return fail(err)
+			}
+			if err := sw.SetRow(cell, []interface{}{
+				row.StudentID,
+				row.StudentFirstName + " " + row.StudentLastName,
+				common.ComponentsText(row.Grade, subject),
+				fmt.Sprintf("%.1f", row.TotalScore),
+				fmt.Sprintf("%.1f", row.GPAScore),
+				row.LetterGrade,
+				common.GradeResultText(row.Grade),
+				row.SubjectName,
+				row.InstructorFirstName + " " + row.InstructorLastName,
+				row.CreatedAt.Format("2006-01-02 15:04:05"),
+				row.UpdatedAt.Format("2006-01-02 15:04:05"),
+			}); err != nil {
+				return fail(err)
+			}
+			line++
+		}
+
+		if progress != nil {
+			if err := progress(line-2, int(total)); err != nil {
+				return stop(err)
+			}
+		}
+	}
+
+	if err := sw.Flush(); err != nil {
+		return fail(err)
+	}
+
+	return f, nil
+}
+
+// sendExcel streams the file to the response as an attachment and closes it once written.
+func sendExcel(c *fiber.Ctx, f *excelize.File, filename string) error {
+	c.Attachment(filename)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer func() {
+			if err := f.Close(); err != nil {
+				fmt.Println(err)
+			}
+		}()
+		if err := f.Write(w); err != nil {
+			fmt.Println(err)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// recordGradeHistory appends the change of a grade to its history in the transaction of the change,
+// before is nil on create and after is nil on delete.
+func recordGradeHistory(tx *gorm.DB, c *fiber.Ctx, action string, before *entity.Grade, after *entity.Grade, reason string) error {
+	history := newGradeHistory(c, action, before, after, reason)
+	return tx.Create(&history).Error
+}
+
+// newGradeHistory builds the history row of a change without saving it, for changes recorded together.
+func newGradeHistory(c *fiber.Ctx, action string, before *entity.Grade, after *entity.Grade, reason string) entity.GradeHistory {
+	grade := after
+	if grade == nil {
+		grade = before
+	}
+
+	history := entity.GradeHistory{
+		GradeID:        grade.ID,
+		Action:         action,
+		SubjectID:      grade.SubjectID,
+		StudentID:      grade.StudentID,
+		ByInstructorID: grade.ByInstructorID,
+		TermID:         grade.TermID,
+		RegistrationID: grade.RegistrationID,
+		Attempt:        grade.Attempt,
+		GradeCreatedAt: grade.CreatedAt,
+		IP:             c.IP(),
+		Reason:         reason,
+	}
+
+	if before != nil {
+		history.OldProcessScore = &before.ProcessScore
+		history.OldMidtermScore = &before.MidtermScore
+		history.OldFinalScore = &before.FinalScore
+		history.OldProcessStatus = before.ProcessStatus
+		history.OldMidtermStatus = before.MidtermStatus
+		history.OldFinalStatus = before.FinalStatus
+		history.OldComponents = common.GradeComponentScores(*before)
+	}
+	if after != nil {
+		history.NewProcessScore = &after.ProcessScore
+		history.NewMidtermScore = &after.MidtermScore
+		history.NewFinalScore = &after.FinalScore
+		history.NewProcessStatus = after.ProcessStatus
+		history.NewMidtermStatus = after.MidtermStatus
+		history.NewFinalStatus = after.FinalStatus
+		history.NewComponents = common.GradeComponentScores(*after)
+	}
+
+	if currentUserId, ok := c.Locals("currentUserId").(string); ok {
+		if userId, err := strconv.ParseUint(currentUserId, 10, 64); err == nil {
+			id := uint(userId)
+			history.UserID = &id
+		}
+	}
+	if apiKeyId, ok := c.Locals("currentApiKeyId").(uint); ok {
+		history.APIKeyID = &apiKeyId
+	}
+
+	return history
+}
+
+// gradesAsOf rebuilds the grades of a student at the given time from the history. Grades entered before the
+// history was kept start from the old scores of their first recorded change, or their current scores if they
+// never changed since.
+func gradesAsOf(studentId string, at time.Time) ([]entity.Grade, error) {
+	var histories []entity.GradeHistory
+	if err := common.DBConn.Order("created_at, id").Find(&histories, "student_id = ?", studentId).Error; err != nil {
+		return nil, err
+	}
+
+	grades := make(map[uint]*entity.Grade)
+	seen := make(map[uint]bool)
+	for _, history := range histories {
+		if !seen[history.GradeID] {
+			seen[history.GradeID] = true
+			if history.Action != entity.GradeHistoryCreate && !history.GradeCreatedAt.After(at) {
+				grades[history.GradeID] = historyGrade(history, false)
+			}
+		}
+
+		if history.CreatedAt.After(at) {
+			continue
+		}
+
+		if history.Action == entity.GradeHistoryDelete {
+			delete(grades, history.GradeID)
+			continue
+		}
+		grades[history.GradeID] = historyGrade(history, true)
+	}
+
+	var untracked []entity.Grade
+	query := common.DBConn.Where("student_id = ? AND created_at <= ?", studentId, at)
+	if len(seen) > 0 {
+		gradesId := make([]uint, 0, len(seen))
+		for gradeId := range seen {
+			gradesId = append(gradesId, gradeId)
+		}
+		query = query.Where("id NOT IN ?", gradesId)
+	}
+	if err := query.Find(&untracked).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]entity.Grade, 0, len(grades)+len(untracked))
+	for _, grade := range grades {
+		result = append(result, *grade)
+	}
+	result = append(result, untracked...)
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+// historyGrade is the grade after the change of the history row, or before it when after is false.
+func historyGrade(history entity.GradeHistory, after bool) *entity.Grade {
+	processScore, midtermScore, finalScore := history.OldProcessScore, history.OldMidtermScore, history.OldFinalScore
+	processStatus, midtermStatus, finalStatus := history.OldProcessStatus, history.OldMidtermStatus, history.OldFinalStatus
+	components := history.OldComponents
+	if after {
+		processScore, midtermScore, finalScore = history.NewProcessScore, history.NewMidtermScore, history.NewFinalScore
+		processStatus, midtermStatus, finalStatus = history.NewProcessStatus, history.NewMidtermStatus, history.NewFinalStatus
+		components = history.NewComponents
+	}
+	// History recorded before components were configurable has none, the grade falls back to its columns
+	if components == nil {
+		components = make([]entity.GradeComponentScore, 0)
+	}
+
+	grade := &entity.Grade{
+		ProcessStatus:  processStatus,
+		MidtermStatus:  midtermStatus,
+		FinalStatus:    finalStatus,
+		ID:             history.GradeID,
+		Attempt:        history.Attempt,
+		SubjectID:      history.SubjectID,
+		StudentID:      history.StudentID,
+		ByInstructorID: history.ByInstructorID,
+		TermID:         history.TermID,
+		RegistrationID: history.RegistrationID,
+		Components:     components,
+		CreatedAt:      history.GradeCreatedAt,
+		UpdatedAt:      history.CreatedAt,
+	}
+	if processScore != nil {
+		grade.ProcessScore = *processScore
+	}
+	if midtermScore != nil {
+		grade.MidtermScore = *midtermScore
+	}
+	if finalScore != nil {
+		grade.FinalScore = *finalScore
+	}
+	return grade
+}
+
+// [GET] /api/grades/:id/history
+func GradeHistoryGetById(c *fiber.Ctx) error {
+	gradeId := c.Params("id")
+	var histories []entity.GradeHistory
+
+	if err := common.DBConn.Order("created_at, id").Find(&histories, "grade_id = ?", gradeId).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	// The history outlives a deleted grade, so the scope is checked on the recorded subject
+	subjectId := ""
+	if len(histories) > 0 {
+		subjectId = histories[0].SubjectID
+	} else {
+		var grade entity.Grade
+		if err := common.DBConn.Select("subject_id").First(&grade, "id = ?", gradeId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy bảng điểm")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+		subjectId = grade.SubjectID
+	}
+
+	if err := checkSubjectScope(c, subjectId); err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", histories))
+}
+
+// [GET] /api/students/:id/grades/as-of?at=2024-01-31T00:00:00Z
+func StudentGradeAsOfGetById(c *fiber.Ctx) error {
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Thời điểm không hợp lệ, vui lòng dùng định dạng RFC3339")
+	}
+
+	studentId := c.Params("id")
+	var student entity.Student
+
+	if err := common.DBConn.Select("id", "department_id").First(&student, "id = ?", studentId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy sinh viên")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := checkDepartmentScope(c, student.DepartmentID); err != nil {
+		return err
+	}
+
+	grades, err := gradesAsOf(student.ID, at)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := fillGradeResults(grades); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", grades))
+}
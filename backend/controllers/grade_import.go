@@ -0,0 +1,403 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"math"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	gradeTemplateSheet     = "Grades"
+	gradeTemplateInfoSheet = "Info"
+
+	gradeTemplateTermIdLabel = "Mã học kỳ"
+
+	gradeTemplateStudentIdHeader   = "Mã sinh viên"
+	gradeTemplateStudentNameHeader = "Tên sinh viên"
+)
+
+// The component columns of the template are headed by the name and the code of the component, as "Điểm cuối kỳ [final]"
+var gradeTemplateComponentHeader = regexp.MustCompile(`\[([^\[\]]+)\]\s*$`)
+
+type gradeImportError struct {
+	Cell   string `json:"cell"`
+	Row    int    `json:"row"`
+	Column string `json:"column"`
+	Error  string `json:"error"`
+}
+
+type gradeImportReport struct {
+	DryRun   bool               `json:"dry_run"`
+	Valid    bool               `json:"valid"`
+	Rows     int                `json:"rows"`
+	Imported int                `json:"imported"`
+	Skipped  []int              `json:"skipped"`
+	Errors   []gradeImportError `json:"errors"`
+	Results  []gradeBatchResult `json:"results"`
+}
+
+type gradeTemplateColumn struct {
+	idx  int
+	code string
+}
+
+// findGradeTemplateSubject loads the subject of a grade template with its components and the term of the template,
+// after the access checks shared by the download and the import.
+func findGradeTemplateSubject(c *fiber.Ctx) (*entity.Subject, *entity.AcademicTerm, error) {
+	subjectId := c.Params("id")
+
+	if err := checkSubjectScope(c, subjectId); err != nil {
+		return nil, nil, err
+	}
+
+	term, err := openTerm(uint(c.QueryInt("term_id")))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkInstructorAssignment(c, subjectId, &term.ID); err != nil {
+		return nil, nil, err
+	}
+
+	var subject entity.Subject
+	if err := common.DBConn.Preload("Components", preloadAssessmentComponents).First(&subject, "id = ?", subjectId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy môn học")
+		}
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return &subject, term, nil
+}
+
+func gradeTemplateCell(row []string, idx int) string {
+	if idx < 0 || idx >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[idx])
+}
+
+// parseGradeTemplateCell reads a component cell, either a score on the 10-point scale or a component status.
+func parseGradeTemplateCell(text string) (float64, string, error) {
+	if status, ok := common.ParseComponentStatus(text); ok {
+		return 0, status, nil
+	}
+
+	score, err := strconv.ParseFloat(strings.Replace(text, ",", ".", 1), 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, "", errors.New("Điểm phải là số hoặc một trong các trạng thái: Vắng có phép, Vắng không phép, Miễn, Chưa hoàn thành, Cấm thi")
+	}
+	if score < 0 || score > 10 {
+		return 0, "", errors.New("Điểm phải từ 0 đến 10")
+	}
+
+	return score, "", nil
+}
+
+// [GET] /api/grades/template/subject/:id
+func GradeTemplateBySubjectId(c *fiber.Ctx) error {
+	subject, term, err := findGradeTemplateSubject(c)
+	if err != nil {
+		return err
+	}
+
+	var students []entity.Student
+	if err := common.DBConn.Where("id IN (?)", common.DBConn.Model(&entity.StudentRegistration{}).Select("student_id").
+		Where("subject_id = ? AND term_id = ?", subject.ID, term.ID)).Order("id").Find(&students).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	components := common.SubjectComponents(*subject)
+
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	if err := f.SetSheetName("Sheet1", gradeTemplateSheet); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+	}
+
+	lastColumn, err := excelize.ColumnNumberToName(len(components) + 2)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+	}
+
+	if err := f.SetColWidth(gradeTemplateSheet, "A", lastColumn, 20); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+	}
+
+	headers := []string{gradeTemplateStudentIdHeader, gradeTemplateStudentNameHeader}
+	for _, component := range components {
+		headers = append(headers, fmt.Sprintf("%s (%d%%) [%s]", component.Name, component.Percentage, component.Code))
+	}
+
+	if err := f.SetSheetRow(gradeTemplateSheet, "A1", &headers); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+	}
+
+	for idx, student := range students {
+		cell, err := excelize.CoordinatesToCellName(1, idx+2)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+		}
+		if err := f.SetSheetRow(gradeTemplateSheet, cell, &[]string{student.ID, student.FirstName + " " + student.LastName}); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+		}
+	}
+
+	// The info sheet ties the file to its subject offering and explains the accepted values
+	if _, err := f.NewSheet(gradeTemplateInfoSheet); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+	}
+
+	info := [][]string{
+		{"Mã môn học", subject.ID},
+		{gradeTemplateTermIdLabel, strconv.FormatUint(uint64(term.ID), 10)},
+		{"Môn học", subject.Name},
+		{"Học kỳ", term.Code()},
+		{"Điểm", "Số từ 0 đến 10, hoặc một trong các trạng thái bên dưới"},
+	}
+	for _, status := range []string{common.ComponentAbsentExcused, common.ComponentAbsentUnexcused, common.ComponentExempt, common.ComponentIncomplete, common.ComponentBanned} {
+		info = append(info, []string{"", common.ComponentStatusText(status)})
+	}
+	for idx, row := range info {
+		cell, err := excelize.CoordinatesToCellName(1, idx+1)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+		}
+		if err := f.SetSheetRow(gradeTemplateInfoSheet, cell, &row); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+		}
+	}
+
+	if err := f.SetColWidth(gradeTemplateInfoSheet, "A", "B", 30); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err))
+	}
+
+	c.Attachment(fmt.Sprintf("BangDiem_%s_%d-%d.xlsx", subject.ID, term.Year, term.Semester))
+	return f.Write(c.Response().BodyWriter())
+}
+
+// [POST] /api/grades/import/subject/:id
+func GradeImportBySubjectId(c *fiber.Ctx) error {
+	subject, term, err := findGradeTemplateSubject(c)
+	if err != nil {
+		return err
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Vui lòng chọn file excel")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Không đọc được file excel")
+	}
+	defer file.Close()
+
+	f, err := excelize.OpenReader(file)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "File excel không hợp lệ")
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	// The Info sheet of the template names the subject and term the file was made for, a file without them is refused
+	info, err := f.GetRows(gradeTemplateInfoSheet)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "File excel không có sheet "+gradeTemplateInfoSheet)
+	}
+	infoCell := func(row int, col int) string {
+		if row >= len(info) || col >= len(info[row]) {
+			return ""
+		}
+		return strings.TrimSpace(info[row][col])
+	}
+	if infoCell(0, 1) != subject.ID {
+		return fiber.NewError(fiber.StatusBadRequest, "File excel không phải bảng điểm của môn học này")
+	}
+	if infoCell(1, 0) != gradeTemplateTermIdLabel || infoCell(1, 1) != strconv.FormatUint(uint64(term.ID), 10) {
+		return fiber.NewError(fiber.StatusBadRequest, "File excel không phải bảng điểm của học kỳ này")
+	}
+
+	rows, err := f.GetRows(gradeTemplateSheet)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "File excel không có sheet "+gradeTemplateSheet)
+	}
+	if len(rows) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "File excel không có dòng tiêu đề")
+	}
+
+	report := gradeImportReport{
+		DryRun:  c.QueryBool("dry_run"),
+		Skipped: make([]int, 0),
+		Errors:  make([]gradeImportError, 0),
+		Results: make([]gradeBatchResult, 0),
+	}
+	cellError := func(col int, row int, column string, message string) {
+		cell, _ := excelize.CoordinatesToCellName(col+1, row)
+		report.Errors = append(report.Errors, gradeImportError{Cell: cell, Row: row, Column: column, Error: message})
+	}
+
+	codes := make(map[string]bool)
+	for _, component := range common.SubjectComponents(*subject) {
+		codes[component.Code] = true
+	}
+
+	studentIdColumn := -1
+	componentColumns := make([]gradeTemplateColumn, 0, len(codes))
+	columnCodes := make(map[string]bool, len(codes))
+	headers := rows[0]
+	for idx, header := range headers {
+		header = strings.TrimSpace(header)
+		if header == gradeTemplateStudentIdHeader {
+			studentIdColumn = idx
+			continue
+		}
+
+		match := gradeTemplateComponentHeader.FindStringSubmatch(header)
+		if match == nil {
+			continue
+		}
+		if !codes[match[1]] {
+			cellError(idx, 1, header, "Môn học không có thành phần điểm "+match[1])
+			continue
+		}
+		if columnCodes[match[1]] {
+			cellError(idx, 1, header, "Thành phần điểm "+match[1]+" bị trùng")
+			continue
+		}
+		columnCodes[match[1]] = true
+		componentColumns = append(componentColumns, gradeTemplateColumn{idx: idx, code: match[1]})
+	}
+
+	if studentIdColumn < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "File excel thiếu cột "+gradeTemplateStudentIdHeader)
+	}
+
+	missing := make([]string, 0)
+	for _, component := range common.SubjectComponents(*subject) {
+		if !columnCodes[component.Code] {
+			missing = append(missing, component.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "File excel thiếu cột điểm: "+strings.Join(missing, ", "))
+	}
+
+	batchRows := make([]req.GradeBatchRow, 0, len(rows))
+	sheetRows := make([]int, 0, len(rows))
+	for idx, row := range rows[1:] {
+		sheetRow := idx + 2
+
+		empty := true
+		for _, column := range componentColumns {
+			empty = empty && gradeTemplateCell(row, column.idx) == ""
+		}
+		if empty {
+			if gradeTemplateCell(row, studentIdColumn) != "" {
+				report.Skipped = append(report.Skipped, sheetRow)
+			}
+			continue
+		}
+		report.Rows++
+
+		studentId := gradeTemplateCell(row, studentIdColumn)
+		valid := true
+		if studentId == "" {
+			cellError(studentIdColumn, sheetRow, gradeTemplateStudentIdHeader, gradeTemplateStudentIdHeader+" không được để trống")
+			valid = false
+		}
+
+		batchRow := req.GradeBatchRow{StudentID: studentId, Components: make([]req.GradeComponentScore, 0, len(componentColumns))}
+		for _, column := range componentColumns {
+			text := gradeTemplateCell(row, column.idx)
+			if text == "" {
+				cellError(column.idx, sheetRow, headers[column.idx], "Chưa nhập điểm")
+				valid = false
+				continue
+			}
+
+			score, status, err := parseGradeTemplateCell(text)
+			if err != nil {
+				cellError(column.idx, sheetRow, headers[column.idx], err.Error())
+				valid = false
+				continue
+			}
+			batchRow.Components = append(batchRow.Components, req.GradeComponentScore{Code: column.code, Score: score, Status: status})
+		}
+
+		if valid {
+			batchRows = append(batchRows, batchRow)
+			sheetRows = append(sheetRows, sheetRow)
+		}
+	}
+
+	if report.Rows == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "File excel không có điểm nào")
+	}
+
+	var batch *gradeBatch
+	if len(batchRows) > 0 {
+		batch, err = prepareGradeBatch(c, &req.GradeBatch{
+			SubjectID:      subject.ID,
+			ByInstructorID: c.Query("by_instructor_id"),
+			TermID:         term.ID,
+			Reason:         c.Query("reason"),
+			Rows:           batchRows,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Rows of the batch are reported with their row in the sheet
+		for i := range batch.results {
+			batch.results[i].Row = sheetRows[i]
+			if batch.results[i].Error != "" {
+				cellError(studentIdColumn, sheetRows[i], gradeTemplateStudentIdHeader, batch.results[i].Error)
+			}
+		}
+		report.Results = batch.results
+	}
+
+	report.Valid = len(report.Errors) == 0
+	if report.DryRun {
+		return c.JSON(common.NewResponse(fiber.StatusOK, "Success", report))
+	}
+
+	if !report.Valid {
+		return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(fiber.StatusBadRequest,
+			fmt.Sprintf("Có %d ô không hợp lệ, chưa có điểm nào được lưu", len(report.Errors)), report))
+	}
+
+	if err := batch.save(c); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi lưu điểm")
+	}
+
+	grades := make([]*entity.Grade, 0, len(batch.results))
+	for _, result := range batch.results {
+		grades = append(grades, result.Grade)
+	}
+	if err := fillGradeResultRefs(grades); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+	report.Imported = len(grades)
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", report))
+}
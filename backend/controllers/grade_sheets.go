@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"strconv"
+	"time"
+)
+
+// requestUserId returns the user behind the request, workflow steps are recorded against a person and API keys have none.
+func requestUserId(c *fiber.Ctx) (uint, error) {
+	currentUserId, _ := c.Locals("currentUserId").(string)
+	userId, err := strconv.ParseUint(currentUserId, 10, 64)
+	if err != nil || userId == 0 {
+		return 0, fiber.NewError(fiber.StatusForbidden, "Thao tác này cần đăng nhập bằng tài khoản người dùng")
+	}
+	return uint(userId), nil
+}
+
+// gradeSheetStatus returns the status of the grades of a subject in a term. Grades entered before terms existed
+// count as published, a subject offering without a sheet yet is still a draft.
+func gradeSheetStatus(db *gorm.DB, subjectId string, termId *uint) (string, error) {
+	if termId == nil {
+		return entity.GradeSheetStatusPublished, nil
+	}
+
+	var gradeSheet entity.GradeSheet
+	if err := db.Select("status").First(&gradeSheet, "subject_id = ? AND term_id = ?", subjectId, *termId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.GradeSheetStatusDraft, nil
+		}
+		return "", err
+	}
+
+	return gradeSheet.Status, nil
+}
+
+// checkGradeEditable allows grade changes only while the sheet is a draft.
+func checkGradeEditable(subjectId string, termId *uint) error {
+	status, err := gradeSheetStatus(common.DBConn, subjectId, termId)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	switch status {
+	case entity.GradeSheetStatusDraft:
+		return nil
+	case entity.GradeSheetStatusPublished:
+		return fiber.NewError(fiber.StatusBadRequest, "Bảng điểm đã khóa, vui lòng tạo yêu cầu điều chỉnh điểm")
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "Bảng điểm đã được nộp, không thể chỉnh sửa")
+	}
+}
+
+// ensureGradeSheet creates the draft sheet of a subject offering when its first grade is entered.
+func ensureGradeSheet(db *gorm.DB, subjectId string, termId uint) error {
+	gradeSheet := entity.GradeSheet{SubjectID: subjectId, TermID: termId, Status: entity.GradeSheetStatusDraft}
+	return db.Where("subject_id = ? AND term_id = ?", subjectId, termId).FirstOrCreate(&gradeSheet).Error
+}
+
+// scopePublishedGrades limits a grade query to the grades students may see.
+func scopePublishedGrades(db *gorm.DB) *gorm.DB {
+	return db.Where("grades.term_id IS NULL OR EXISTS (?)", common.DBConn.Model(&entity.GradeSheet{}).Select("1").
+		Where("grade_sheets.subject_id = grades.subject_id AND grade_sheets.term_id = grades.term_id AND grade_sheets.status = ?", entity.GradeSheetStatusPublished))
+}
+
+func findGradeSheet(c *fiber.Ctx) (*entity.GradeSheet, error) {
+	gradeSheetId := c.Params("id")
+	var gradeSheet entity.GradeSheet
+
+	if err := common.DBConn.First(&gradeSheet, "id = ?", gradeSheetId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy bảng điểm")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := checkSubjectScope(c, gradeSheet.SubjectID); err != nil {
+		return nil, err
+	}
+
+	return &gradeSheet, nil
+}
+
+// moveGradeSheet changes the status only if nobody moved the sheet in the meantime, then runs in the same
+// transaction to lock or unlock the results of the grades of the sheet.
+func moveGradeSheet(gradeSheet *entity.GradeSheet, from []string, updates map[string]any, then func(tx *gorm.DB, gradeSheet *entity.GradeSheet) error) error {
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.GradeSheet{}).
+			Where("id = ? AND status IN ?", gradeSheet.ID, from).
+			Updates(updates)
+		if result.Error != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật bảng điểm")
+		}
+		if result.RowsAffected == 0 {
+			return fiber.NewError(fiber.StatusConflict, "Trạng thái bảng điểm đã thay đổi, vui lòng tải lại")
+		}
+		if err := then(tx, gradeSheet); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật bảng điểm")
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := common.DBConn.First(gradeSheet, "id = ?", gradeSheet.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return nil
+}
+
+// lockGradeResults stores the results of the grades of the sheet as they are computed now. The department reviews
+// and publishes these, later changes to the components, weights or grading scale of the subject no longer move them.
+func lockGradeResults(tx *gorm.DB, gradeSheet *entity.GradeSheet) error {
+	var grades []entity.Grade
+	if err := tx.Find(&grades, "subject_id = ? AND term_id = ? AND result_locked_at IS NULL", gradeSheet.SubjectID, gradeSheet.TermID).Error; err != nil {
+		return err
+	}
+
+	if err := fillGradeResults(grades); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, grade := range grades {
+		if err := tx.Model(&entity.Grade{}).Where("id = ?", grade.ID).UpdateColumns(map[string]any{
+			"total_score":      grade.TotalScore,
+			"gpa_score":        grade.GPAScore,
+			"letter_grade":     grade.LetterGrade,
+			"passed":           grade.Passed,
+			"result_status":    grade.ResultStatus,
+			"result_locked_at": now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unlockGradeResults lets the results of a returned sheet follow its subject again while it is a draft.
+func unlockGradeResults(tx *gorm.DB, gradeSheet *entity.GradeSheet) error {
+	return tx.Model(&entity.Grade{}).
+		Where("subject_id = ? AND term_id = ?", gradeSheet.SubjectID, gradeSheet.TermID).
+		UpdateColumn("result_locked_at", nil).Error
+}
+
+// [GET] /api/grade-sheets
+func GradeSheetGetAll(c *fiber.Ctx) error {
+	var gradeSheets []entity.GradeSheet
+
+	query := common.DBConn.Scopes(scopeSubjectDepartment(c, "subject_id"), scopeTerm(c))
+	if subjectId := c.Query("subject_id"); subjectId != "" {
+		query = query.Where("subject_id = ?", subjectId)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&gradeSheets).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", gradeSheets))
+}
+
+// [GET] /api/grade-sheets/:id
+func GradeSheetGetById(c *fiber.Ctx) error {
+	gradeSheet, err := findGradeSheet(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", gradeSheet))
+}
+
+// [POST] /api/grade-sheets/:id/submit
+func GradeSheetSubmitById(c *fiber.Ctx) error {
+	userId, err := requestUserId(c)
+	if err != nil {
+		return err
+	}
+
+	gradeSheet, err := findGradeSheet(c)
+	if err != nil {
+		return err
+	}
+
+	if err := checkInstructorAssignment(c, gradeSheet.SubjectID, &gradeSheet.TermID); err != nil {
+		return err
+	}
+
+	if gradeSheet.Status != entity.GradeSheetStatusDraft {
+		return fiber.NewError(fiber.StatusBadRequest, "Chỉ có thể nộp bảng điểm đang nhập")
+	}
+
+	// Every registered student needs a grade before the sheet goes to the department
+	var missing int64
+	if err := common.DBConn.Model(&entity.StudentRegistration{}).
+		Where("subject_id = ? AND term_id = ?", gradeSheet.SubjectID, gradeSheet.TermID).
+		Where("id NOT IN (?)", common.DBConn.Model(&entity.Grade{}).Select("registration_id").Where("registration_id IS NOT NULL")).
+		Count(&missing).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if missing > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Còn "+strconv.FormatInt(missing, 10)+" sinh viên chưa có điểm")
+	}
+
+	if err := moveGradeSheet(gradeSheet, []string{entity.GradeSheetStatusDraft}, map[string]any{
+		"status":          entity.GradeSheetStatusSubmitted,
+		"submitted_by_id": userId,
+		"submitted_at":    time.Now(),
+		"return_reason":   "",
+	}, lockGradeResults); err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", gradeSheet))
+}
+
+// [POST] /api/grade-sheets/:id/approve
+func GradeSheetApproveById(c *fiber.Ctx) error {
+	userId, err := requestUserId(c)
+	if err != nil {
+		return err
+	}
+
+	gradeSheet, err := findGradeSheet(c)
+	if err != nil {
+		return err
+	}
+
+	if gradeSheet.Status != entity.GradeSheetStatusSubmitted {
+		return fiber.NewError(fiber.StatusBadRequest, "Chỉ có thể duyệt bảng điểm đã nộp")
+	}
+
+	if err := moveGradeSheet(gradeSheet, []string{entity.GradeSheetStatusSubmitted}, map[string]any{
+		"status":         entity.GradeSheetStatusApproved,
+		"approved_by_id": userId,
+		"approved_at":    time.Now(),
+	}, lockGradeResults); err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", gradeSheet))
+}
+
+// [POST] /api/grade-sheets/:id/return
+func GradeSheetReturnById(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.GradeSheetReturn](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if _, err := requestUserId(c); err != nil {
+		return err
+	}
+
+	gradeSheet, err := findGradeSheet(c)
+	if err != nil {
+		return err
+	}
+
+	if gradeSheet.Status != entity.GradeSheetStatusSubmitted && gradeSheet.Status != entity.GradeSheetStatusApproved {
+		return fiber.NewError(fiber.StatusBadRequest, "Chỉ có thể trả lại bảng điểm đã nộp hoặc đã duyệt")
+	}
+
+	if err := moveGradeSheet(gradeSheet, []string{entity.GradeSheetStatusSubmitted, entity.GradeSheetStatusApproved}, map[string]any{
+		"status":         entity.GradeSheetStatusDraft,
+		"return_reason":  bodyData.Reason,
+		"approved_by_id": nil,
+		"approved_at":    nil,
+	}, unlockGradeResults); err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", gradeSheet))
+}
+
+// [POST] /api/grade-sheets/:id/publish
+func GradeSheetPublishById(c *fiber.Ctx) error {
+	userId, err := requestUserId(c)
+	if err != nil {
+		return err
+	}
+
+	gradeSheet, err := findGradeSheet(c)
+	if err != nil {
+		return err
+	}
+
+	if gradeSheet.Status != entity.GradeSheetStatusApproved {
+		return fiber.NewError(fiber.StatusBadRequest, "Chỉ có thể công bố bảng điểm đã duyệt")
+	}
+
+	if err := moveGradeSheet(gradeSheet, []string{entity.GradeSheetStatusApproved}, map[string]any{
+		"status":          entity.GradeSheetStatusPublished,
+		"published_by_id": userId,
+		"published_at":    time.Now(),
+	}, lockGradeResults); err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", gradeSheet))
+}
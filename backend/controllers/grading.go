@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+)
+
+// subjectGradingScales resolves the grading scale of every subject: its own, else the one of its department,
+// else the default scale.
+func subjectGradingScales(subjects []entity.Subject) (map[string]entity.GradingScale, error) {
+	scales := make(map[string]entity.GradingScale, len(subjects))
+	if len(subjects) == 0 {
+		return scales, nil
+	}
+
+	departmentsId := make([]uint, 0, len(subjects))
+	for _, subject := range subjects {
+		departmentsId = append(departmentsId, subject.DepartmentID)
+	}
+
+	var departments []entity.Department
+	if err := common.DBConn.Select("id", "grading_scale_id").Find(&departments, "id IN ?", departmentsId).Error; err != nil {
+		return nil, err
+	}
+
+	departmentScaleIds := make(map[uint]uint, len(departments))
+	scaleIds := make([]uint, 0)
+	for _, department := range departments {
+		if department.GradingScaleID != nil {
+			departmentScaleIds[department.ID] = *department.GradingScaleID
+			scaleIds = append(scaleIds, *department.GradingScaleID)
+		}
+	}
+	for _, subject := range subjects {
+		if subject.GradingScaleID != nil {
+			scaleIds = append(scaleIds, *subject.GradingScaleID)
+		}
+	}
+
+	scalesById := make(map[uint]entity.GradingScale)
+	if len(scaleIds) > 0 {
+		var gradingScales []entity.GradingScale
+		if err := common.DBConn.Preload("Bands", func(db *gorm.DB) *gorm.DB {
+			return db.Order("min_score desc")
+		}).Find(&gradingScales, "id IN ?", scaleIds).Error; err != nil {
+			return nil, err
+		}
+
+		for _, scale := range gradingScales {
+			scalesById[scale.ID] = scale
+		}
+	}
+
+	for _, subject := range subjects {
+		scale := common.DefaultGradingScale
+		if departmentScale, ok := scalesById[departmentScaleIds[subject.DepartmentID]]; ok {
+			scale = departmentScale
+		}
+		if subject.GradingScaleID != nil {
+			if subjectScale, ok := scalesById[*subject.GradingScaleID]; ok {
+				scale = subjectScale
+			}
+		}
+		scales[subject.ID] = scale
+	}
+
+	return scales, nil
+}
+
+func preloadAssessmentComponents(db *gorm.DB) *gorm.DB {
+	return db.Order("position, id")
+}
+
+// loadSubjectComponents attaches the assessment components to the subjects that were loaded without them.
+func loadSubjectComponents(subjects []entity.Subject) error {
+	subjectsId := make([]string, 0, len(subjects))
+	for _, subject := range subjects {
+		if subject.Components == nil {
+			subjectsId = append(subjectsId, subject.ID)
+		}
+	}
+	if len(subjectsId) == 0 {
+		return nil
+	}
+
+	var components []entity.AssessmentComponent
+	if err := preloadAssessmentComponents(common.DBConn).Find(&components, "subject_id IN ?", subjectsId).Error; err != nil {
+		return err
+	}
+
+	bySubject := make(map[string][]entity.AssessmentComponent)
+	for _, component := range components {
+		bySubject[component.SubjectID] = append(bySubject[component.SubjectID], component)
+	}
+
+	for i := range subjects {
+		if subjects[i].Components == nil {
+			subjects[i].Components = bySubject[subjects[i].ID]
+			if subjects[i].Components == nil {
+				subjects[i].Components = make([]entity.AssessmentComponent, 0)
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadGradeComponents attaches the component scores to the grades that were loaded without them. Grades rebuilt
+// from their history come with their own, possibly empty, components and are left alone.
+func loadGradeComponents(grades []*entity.Grade) error {
+	gradesId := make([]uint, 0, len(grades))
+	for _, grade := range grades {
+		if grade.Components == nil && grade.ID != 0 {
+			gradesId = append(gradesId, grade.ID)
+		}
+	}
+	if len(gradesId) == 0 {
+		return nil
+	}
+
+	var scores []entity.GradeComponentScore
+	if err := common.DBConn.Order("id").Find(&scores, "grade_id IN ?", gradesId).Error; err != nil {
+		return err
+	}
+
+	byGrade := make(map[uint][]entity.GradeComponentScore)
+	for _, score := range scores {
+		byGrade[score.GradeID] = append(byGrade[score.GradeID], score)
+	}
+
+	for _, grade := range grades {
+		if grade.Components == nil {
+			grade.Components = byGrade[grade.ID]
+			if grade.Components == nil {
+				grade.Components = make([]entity.GradeComponentScore, 0)
+			}
+		}
+	}
+
+	return nil
+}
+
+// fillGradeResultRefs computes the total score and letter grade of every grade, loading the subjects in one query.
+func fillGradeResultRefs(grades []*entity.Grade) error {
+	if len(grades) == 0 {
+		return nil
+	}
+
+	if err := loadGradeComponents(grades); err != nil {
+		return err
+	}
+
+	subjectsId := make([]string, 0, len(grades))
+	for _, grade := range grades {
+		subjectsId = append(subjectsId, grade.SubjectID)
+	}
+
+	var subjects []entity.Subject
+	if err := common.DBConn.Preload("Components", preloadAssessmentComponents).Find(&subjects, "id IN ?", subjectsId).Error; err != nil {
+		return err
+	}
+
+	scales, err := subjectGradingScales(subjects)
+	if err != nil {
+		return err
+	}
+
+	subjectsById := make(map[string]entity.Subject, len(subjects))
+	for _, subject := range subjects {
+		subjectsById[subject.ID] = subject
+	}
+
+	for _, grade := range grades {
+		common.ApplyGradeResult(grade, subjectsById[grade.SubjectID], scales[grade.SubjectID])
+	}
+
+	return nil
+}
+
+func fillGradeResults(grades []entity.Grade) error {
+	refs := make([]*entity.Grade, 0, len(grades))
+	for i := range grades {
+		refs = append(refs, &grades[i])
+	}
+	return fillGradeResultRefs(refs)
+}
+
+func fillGradeResult(grade *entity.Grade) error {
+	return fillGradeResultRefs([]*entity.Grade{grade})
+}
+
+// fillStudentGradeResults computes the preloaded grades of the students.
+func fillStudentGradeResults(students []entity.Student) error {
+	refs := make([]*entity.Grade, 0)
+	for i := range students {
+		for j := range students[i].Grades {
+			refs = append(refs, &students[i].Grades[j])
+		}
+	}
+	return fillGradeResultRefs(refs)
+}
+
+// fillInstructorGradeResults computes the preloaded grades of the instructors.
+func fillInstructorGradeResults(instructors []entity.Instructor) error {
+	refs := make([]*entity.Grade, 0)
+	for i := range instructors {
+		for j := range instructors[i].Grades {
+			refs = append(refs, &instructors[i].Grades[j])
+		}
+	}
+	return fillGradeResultRefs(refs)
+}
+
+// fillSubjectGradeResults computes the preloaded grades of the subjects, which are already known.
+func fillSubjectGradeResults(subjects []entity.Subject) error {
+	if err := loadSubjectComponents(subjects); err != nil {
+		return err
+	}
+
+	refs := make([]*entity.Grade, 0)
+	for i := range subjects {
+		for j := range subjects[i].Grades {
+			refs = append(refs, &subjects[i].Grades[j])
+		}
+	}
+	if err := loadGradeComponents(refs); err != nil {
+		return err
+	}
+
+	scales, err := subjectGradingScales(subjects)
+	if err != nil {
+		return err
+	}
+
+	for i := range subjects {
+		for j := range subjects[i].Grades {
+			common.ApplyGradeResult(&subjects[i].Grades[j], subjects[i], scales[subjects[i].ID])
+		}
+	}
+
+	return nil
+}
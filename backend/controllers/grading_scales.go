@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+)
+
+func preloadGradingScaleBands(db *gorm.DB) *gorm.DB {
+	return db.Order("min_score desc")
+}
+
+// gradingScaleBands checks that the bands cover every score from 0 and that a higher band never scores lower,
+// and returns them ordered from the highest.
+func gradingScaleBands(bodyBands []req.GradingScaleBand) ([]entity.GradingScaleBand, error) {
+	bands := make([]entity.GradingScaleBand, 0, len(bodyBands))
+	letters := make(map[string]bool, len(bodyBands))
+	minScores := make(map[float64]bool, len(bodyBands))
+
+	for _, band := range bodyBands {
+		if letters[band.Letter] {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Điểm chữ "+band.Letter+" bị trùng")
+		}
+		if minScores[band.MinScore] {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Điểm tối thiểu của các mức bị trùng")
+		}
+		letters[band.Letter] = true
+		minScores[band.MinScore] = true
+
+		bands = append(bands, entity.GradingScaleBand{
+			Letter:   band.Letter,
+			MinScore: band.MinScore,
+			Point:    band.Point,
+			Passed:   band.Passed,
+		})
+	}
+
+	if !minScores[0] {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Thang điểm phải có một mức bắt đầu từ 0")
+	}
+
+	common.SortGradingScaleBands(bands)
+
+	for i := 1; i < len(bands); i++ {
+		if bands[i].Point > bands[i-1].Point {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Điểm hệ 4 của mức thấp hơn không được lớn hơn mức cao hơn")
+		}
+		if bands[i].Passed && !bands[i-1].Passed {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Mức thấp hơn không được đạt khi mức cao hơn không đạt")
+		}
+	}
+
+	return bands, nil
+}
+
+func checkGradingScaleNameUnique(name string, excludeId uint) error {
+	var gradingScale entity.GradingScale
+	if err := common.DBConn.Select("id").First(&gradingScale, "name = ? and id <> ?", name, excludeId).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+	}
+
+	if gradingScale.ID != 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Tên thang điểm đã tồn tại")
+	}
+
+	return nil
+}
+
+// findGradingScale loads the scale to attach, nil detaches the current one.
+func findGradingScale(gradingScaleId *uint) error {
+	if gradingScaleId == nil {
+		return nil
+	}
+
+	var gradingScale entity.GradingScale
+	if err := common.DBConn.Select("id").First(&gradingScale, "id = ?", *gradingScaleId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy thang điểm")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return nil
+}
+
+// gradingScaleGraded tells whether a subject has grades while the scale decides their results, directly or through
+// the department of a subject without a scale of its own.
+func gradingScaleGraded(gradingScaleId uint) (bool, error) {
+	subjectsOfScale := common.DBConn.Model(&entity.Subject{}).Select("subjects.id").
+		Joins("JOIN departments ON departments.id = subjects.department_id").
+		Where("subjects.grading_scale_id = ? OR (subjects.grading_scale_id IS NULL AND departments.grading_scale_id = ?)", gradingScaleId, gradingScaleId)
+
+	var count int64
+	if err := common.DBConn.Model(&entity.Grade{}).Where("subject_id IN (?)", subjectsOfScale).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func sameGradingScaleBands(a []entity.GradingScaleBand, b []entity.GradingScaleBand) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Letter != b[i].Letter || a[i].MinScore != b[i].MinScore || a[i].Point != b[i].Point || a[i].Passed != b[i].Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// [GET] /api/grading-scales
+func GradingScaleGetAll(c *fiber.Ctx) error {
+	var gradingScales []entity.GradingScale
+
+	if err := common.DBConn.Preload("Bands", preloadGradingScaleBands).Find(&gradingScales).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", gradingScales))
+}
+
+// [GET] /api/grading-scales/default
+func GradingScaleGetDefault(c *fiber.Ctx) error {
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", common.DefaultGradingScale))
+}
+
+// [GET] /api/grading-scales/:id
+func GradingScaleGetById(c *fiber.Ctx) error {
+	gradingScaleId := c.Params("id")
+	var gradingScale entity.GradingScale
+
+	if err := common.DBConn.Preload("Bands", preloadGradingScaleBands).First(&gradingScale, "id = ?", gradingScaleId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy thang điểm")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", gradingScale))
+}
+
+// [POST] /api/grading-scales
+func GradingScaleCreate(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.GradingScaleCreate](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	bands, err := gradingScaleBands(bodyData.Bands)
+	if err != nil {
+		return err
+	}
+
+	if err := checkGradingScaleNameUnique(bodyData.Name, 0); err != nil {
+		return err
+	}
+
+	newGradingScale := entity.GradingScale{
+		Name:         bodyData.Name,
+		RoundingStep: bodyData.RoundingStep,
+		Bands:        bands,
+	}
+
+	if err := common.DBConn.Create(&newGradingScale).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo thang điểm")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", newGradingScale))
+}
+
+// [PUT] /api/grading-scales/:id
+func GradingScaleUpdateById(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.GradingScaleUpdateById](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	gradingScaleId := c.Params("id")
+	var gradingScale entity.GradingScale
+
+	if err := common.DBConn.Preload("Bands", preloadGradingScaleBands).First(&gradingScale, "id = ?", gradingScaleId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy thang điểm")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	bands, err := gradingScaleBands(bodyData.Bands)
+	if err != nil {
+		return err
+	}
+
+	// A scale that graded students only gets a new name, new rules go to a new scale attached in its place
+	if bodyData.RoundingStep != gradingScale.RoundingStep || !sameGradingScaleBands(bands, gradingScale.Bands) {
+		graded, err := gradingScaleGraded(gradingScale.ID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+		if graded {
+			return fiber.NewError(fiber.StatusBadRequest, "Thang điểm đã được dùng để chấm điểm, vui lòng tạo thang điểm mới và gán cho khoa hoặc môn học")
+		}
+	}
+
+	if err := checkGradingScaleNameUnique(bodyData.Name, gradingScale.ID); err != nil {
+		return err
+	}
+
+	gradingScale.Name = bodyData.Name
+	gradingScale.RoundingStep = bodyData.RoundingStep
+
+	// The bands are replaced as a whole
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Bands").Save(&gradingScale).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&entity.GradingScaleBand{}, "grading_scale_id = ?", gradingScale.ID).Error; err != nil {
+			return err
+		}
+		for i := range bands {
+			bands[i].GradingScaleID = gradingScale.ID
+		}
+		return tx.Create(&bands).Error
+	}); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật thang điểm")
+	}
+
+	gradingScale.Bands = bands
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", gradingScale))
+}
+
+// [DELETE] /api/grading-scales/:id
+func GradingScaleDeleteById(c *fiber.Ctx) error {
+	gradingScaleId := c.Params("id")
+	var gradingScale entity.GradingScale
+
+	if err := common.DBConn.First(&gradingScale, "id = ?", gradingScaleId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy thang điểm")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	// Departments and subjects using the scale fall back to the next scale that applies
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.Department{}).Where("grading_scale_id = ?", gradingScale.ID).Update("grading_scale_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.Subject{}).Where("grading_scale_id = ?", gradingScale.ID).Update("grading_scale_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&entity.GradingScaleBand{}, "grading_scale_id = ?", gradingScale.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&gradingScale).Error
+	}); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa thang điểm")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", gradingScale))
+}
+
+// [PUT] /api/departments/:id/grading-scale
+func DepartmentUpdateGradingScaleById(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.UpdateGradingScaleById](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	departmentId := c.Params("id")
+	var department entity.Department
+
+	if err := common.DBConn.First(&department, "id = ?", departmentId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy khoa")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := checkDepartmentScope(c, department.ID); err != nil {
+		return err
+	}
+
+	if err := findGradingScale(bodyData.GradingScaleID); err != nil {
+		return err
+	}
+
+	department.GradingScaleID = bodyData.GradingScaleID
+	if err := common.DBConn.Model(&department).Select("grading_scale_id").Updates(&department).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật khoa")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", department))
+}
+
+// [PUT] /api/subjects/:id/grading-scale
+func SubjectUpdateGradingScaleById(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.UpdateGradingScaleById](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	subjectId := c.Params("id")
+	var subject entity.Subject
+
+	if err := common.DBConn.First(&subject, "id = ?", subjectId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy môn học")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := checkDepartmentScope(c, subject.DepartmentID); err != nil {
+		return err
+	}
+
+	if err := findGradingScale(bodyData.GradingScaleID); err != nil {
+		return err
+	}
+
+	subject.GradingScaleID = bodyData.GradingScaleID
+	if err := common.DBConn.Model(&subject).Select("grading_scale_id").Updates(&subject).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật môn học")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", subject))
+}
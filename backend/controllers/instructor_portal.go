@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+)
+
+// linkedInstructorId returns the instructor linked to the current user, or an empty string when the account is not linked.
+// Instructor accounts always grade under their own name, so one that is not linked yet is refused.
+func linkedInstructorId(c *fiber.Ctx) (string, error) {
+	currentUserId, currentUserIdIsOk := c.Locals("currentUserId").(string)
+
+	if !currentUserIdIsOk {
+		return "", nil
+	}
+
+	var user entity.User
+	if err := common.DBConn.Select("instructor_id", "role").First(&user, "id = ?", currentUserId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fiber.NewError(fiber.StatusUnauthorized, "Không tìm thấy user")
+		}
+		return "", fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if user.InstructorID == nil {
+		if user.Role == entity.RoleInstructor {
+			return "", fiber.NewError(fiber.StatusForbidden, "Tài khoản chưa được liên kết với giảng viên")
+		}
+		return "", nil
+	}
+
+	return *user.InstructorID, nil
+}
+
+func currentInstructor(c *fiber.Ctx) (*entity.Instructor, error) {
+	instructorId, err := linkedInstructorId(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if instructorId == "" {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Tài khoản chưa được liên kết với giảng viên")
+	}
+
+	var instructor entity.Instructor
+	if err := common.DBConn.First(&instructor, "id = ?", instructorId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy giảng viên")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return &instructor, nil
+}
+
+// checkInstructorAssignment only lets an account linked to an instructor touch the subject offerings that instructor
+// is assigned to. Grades entered before terms existed have no term, they match the assignments without one.
+func checkInstructorAssignment(c *fiber.Ctx, subjectId string, termId *uint) error {
+	return checkInstructorAssignmentIn(c, subjectId, scopeTermId(termId))
+}
+
+// checkInstructorAssignmentIn is checkInstructorAssignment for the assignments the scope selects.
+func checkInstructorAssignmentIn(c *fiber.Ctx, subjectId string, scope func(db *gorm.DB) *gorm.DB) error {
+	instructorId, err := linkedInstructorId(c)
+	if err != nil {
+		return err
+	}
+
+	if instructorId == "" {
+		return nil
+	}
+
+	var assignment entity.InstructorAssignment
+	if err := common.DBConn.Scopes(scope).First(&assignment, "subject_id = ? and instructor_id = ?", subjectId, instructorId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusForbidden, "Bạn không được phân công dạy môn học này")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return nil
+}
+
+// [GET] /api/me/instructor
+func MeInstructorGet(c *fiber.Ctx) error {
+	instructor, err := currentInstructor(c)
+	if err != nil {
+		return err
+	}
+
+	if err := common.DBConn.Preload("Classes").Preload("Assignments").First(instructor, "id = ?", instructor.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", instructor))
+}
+
+// [GET] /api/me/instructor/subjects
+func MeInstructorSubjectGetAll(c *fiber.Ctx) error {
+	instructor, err := currentInstructor(c)
+	if err != nil {
+		return err
+	}
+
+	var subjects []entity.Subject
+	if err := common.DBConn.Where("id IN (?)", common.DBConn.Model(&entity.InstructorAssignment{}).Select("subject_id").Where("instructor_id = ?", instructor.ID).Scopes(scopeTerm(c))).Find(&subjects).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", subjects))
+}
+
+// [GET] /api/me/instructor/subjects/:id/students
+func MeInstructorSubjectStudentGetAll(c *fiber.Ctx) error {
+	subjectId := c.Params("id")
+
+	if _, err := currentInstructor(c); err != nil {
+		return err
+	}
+
+	if err := checkInstructorAssignmentIn(c, subjectId, scopeTerm(c)); err != nil {
+		return err
+	}
+
+	var students []entity.Student
+	if err := common.DBConn.Preload("Grades", func(db *gorm.DB) *gorm.DB {
+		return db.Where("subject_id = ?", subjectId).Scopes(scopeTerm(c))
+	}).
+		Where("id IN (?)", common.DBConn.Model(&entity.StudentRegistration{}).Select("student_id").Where("subject_id = ?", subjectId).Scopes(scopeTerm(c))).
+		Find(&students).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := fillStudentGradeResults(students); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", students))
+}
+
+// [POST] /api/me/instructor/grades
+func MeInstructorGradeCreate(c *fiber.Ctx) error {
+	if _, err := currentInstructor(c); err != nil {
+		return err
+	}
+
+	return GradeCreate(c)
+}
+
+// [PUT] /api/me/instructor/grades/:id
+func MeInstructorGradeUpdateById(c *fiber.Ctx) error {
+	if _, err := currentInstructor(c); err != nil {
+		return err
+	}
+
+	return GradeUpdateById(c)
+}
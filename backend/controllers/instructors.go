@@ -22,7 +22,11 @@ func generateInstructorID(departmentID uint) string {
 // [GET] /api/instructors
 func InstructorGetAll(c *fiber.Ctx) error {
 	var instructors []entity.Instructor
-	if err := common.DBConn.Preload("Classes").Preload("Grades").Preload("Assignments").Find(&instructors).Error; err != nil {
+	if err := common.DBConn.Preload("Classes").Preload("Grades").Preload("Assignments").Scopes(scopeDepartment(c, "department_id")).Find(&instructors).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := fillInstructorGradeResults(instructors); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -33,11 +37,19 @@ func InstructorGetAll(c *fiber.Ctx) error {
 func InstructorGetAllByDepartmentId(c *fiber.Ctx) error {
 	departmentId := c.Params("id")
 
+	if err := checkDepartmentScopeById(c, departmentId); err != nil {
+		return err
+	}
+
 	var instructors []entity.Instructor
 	if err := common.DBConn.Preload("Grades").Preload("Classes").Preload("Assignments").Find(&instructors, "department_id = ?", departmentId).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := fillInstructorGradeResults(instructors); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", instructors))
 }
 
@@ -53,6 +65,10 @@ func InstructorGetById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, instructor.DepartmentID); err != nil {
+		return err
+	}
+
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", instructor))
 }
 
@@ -64,6 +80,10 @@ func InstructorCreate(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	if err := checkDepartmentScope(c, bodyData.DepartmentID); err != nil {
+		return err
+	}
+
 	today := time.Now()
 	if bodyData.BirthDay.After(today) {
 		return fiber.NewError(fiber.StatusBadRequest, "Ngày sinh không hợp lệ")
@@ -134,6 +154,10 @@ func InstructorUpdateById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, instructor.DepartmentID); err != nil {
+		return err
+	}
+
 	var existInstructor entity.Instructor
 	if err := common.DBConn.First(&existInstructor, "id <> ? and (email = ? or phone = ?)", instructor.ID, bodyData.Email, bodyData.Phone).Error; err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -175,6 +199,10 @@ func InstructorDeleteById(c *fiber.Ctx) error {
 
 	}
 
+	if err := checkDepartmentScope(c, instructor.DepartmentID); err != nil {
+		return err
+	}
+
 	if err := common.DBConn.Delete(&instructor).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa giáo viên")
 	}
@@ -190,7 +218,7 @@ func InstructorDeleteByListId(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	if err := common.DBConn.Where("id IN ?", bodyData.ListId).Delete(&entity.Instructor{}).Error; err != nil {
+	if err := common.DBConn.Where("id IN ?", bodyData.ListId).Scopes(scopeDepartment(c, "department_id")).Delete(&entity.Instructor{}).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy giảng viên")
 		}
@@ -202,7 +230,7 @@ func InstructorDeleteByListId(c *fiber.Ctx) error {
 
 // [DELETE] /api/instructors
 func InstructorDeleteAll(c *fiber.Ctx) error {
-	if err := common.DBConn.Where("1 = 1").Delete(&entity.Instructor{}).Error; err != nil {
+	if err := common.DBConn.Where("1 = 1").Scopes(scopeDepartment(c, "department_id")).Delete(&entity.Instructor{}).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa tất cả giảng viên")
 	}
 
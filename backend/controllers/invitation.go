@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"log"
+	"net/url"
+	"os"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"strconv"
+	"time"
+)
+
+// [GET] /api/users/invitations
+func InvitationGetAll(c *fiber.Ctx) error {
+	var invitations []entity.Invitation
+
+	if err := common.DBConn.Order("id desc").Find(&invitations).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", invitations))
+}
+
+// [POST] /api/users/invitations
+func InvitationCreate(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.InvitationCreate](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if !common.IsValidRole(bodyData.Role) {
+		return fiber.NewError(fiber.StatusBadRequest, "Role không hợp lệ")
+	}
+
+	var existUser entity.User
+	if err := common.DBConn.Unscoped().Select("id").First(&existUser, "email = ?", bodyData.Email).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+	}
+
+	if existUser.ID != 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Email đã tồn tại")
+	}
+
+	currentUserId, _ := c.Locals("currentUserId").(string)
+	invitedById, _ := strconv.Atoi(currentUserId)
+
+	token, err := common.GenerateRandToken(32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo token")
+	}
+
+	ttl := common.GetEnvDuration("INVITATION_TTL", 72*time.Hour)
+	newInvitation := entity.Invitation{
+		TokenHash:   common.HashToken(token),
+		Email:       bodyData.Email,
+		Role:        bodyData.Role,
+		ExpiresAt:   time.Now().Add(ttl),
+		InvitedByID: uint(invitedById),
+	}
+
+	// Inviting the same email again replaces the pending invitation
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.Invitation{}).Where("email = ? and accepted_at IS NULL and revoked_at IS NULL", bodyData.Email).Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&newInvitation).Error
+	}); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo lời mời")
+	}
+
+	link := os.Getenv("CLIENT_URL") + "/accept-invitation?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Xin chào,\n\nBạn được mời tạo tài khoản trên hệ thống quản lý điểm sinh viên. Vui lòng truy cập liên kết sau để đặt tên đăng nhập và mật khẩu, liên kết có hiệu lực trong %v:\n%s", ttl, link)
+
+	if err := common.Mailer.Send(bodyData.Email, "Lời mời tạo tài khoản", body); err != nil {
+		log.Println("Error sending invitation mail:", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi gửi email")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(common.NewResponse(fiber.StatusCreated, "Success", newInvitation))
+}
+
+// [DELETE] /api/users/invitations/:id
+func InvitationDeleteById(c *fiber.Ctx) error {
+	invitationId := c.Params("id")
+
+	result := common.DBConn.Model(&entity.Invitation{}).Where("id = ? and accepted_at IS NULL and revoked_at IS NULL", invitationId).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi thu hồi lời mời")
+	}
+
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy lời mời")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", nil))
+}
+
+// [POST] /api/auth/invitations/accept
+func AuthInvitationAccept(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.AuthInvitationAccept](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var invitation entity.Invitation
+	if err := common.DBConn.First(&invitation, "token_hash = ? and accepted_at IS NULL and revoked_at IS NULL and expires_at > ?", common.HashToken(bodyData.Token), time.Now()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Lời mời không hợp lệ hoặc đã hết hạn")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := common.ValidatePassword(bodyData.Password); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := checkUserUnique(bodyData.UserName, invitation.Email); err != nil {
+		return err
+	}
+
+	hashPassword, hashPasswordErr := bcrypt.GenerateFromPassword([]byte(bodyData.Password), 11)
+
+	if hashPasswordErr != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo tài khoản")
+	}
+
+	newUser := entity.User{
+		FirstName: bodyData.FirstName,
+		LastName:  bodyData.LastName,
+		UserName:  bodyData.UserName,
+		Email:     &invitation.Email,
+		Password:  string(hashPassword),
+		Role:      invitation.Role,
+	}
+
+	errInvitationUsed := errors.New("invitation used")
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		// Marking the invitation accepted first keeps it single-use under concurrent requests
+		result := tx.Model(&entity.Invitation{}).Where("id = ? and accepted_at IS NULL and revoked_at IS NULL", invitation.ID).Update("accepted_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvitationUsed
+		}
+
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&entity.Invitation{}).Where("id = ?", invitation.ID).Update("user_id", newUser.ID).Error
+	}); err != nil {
+		if errors.Is(err, errInvitationUsed) {
+			return fiber.NewError(fiber.StatusBadRequest, "Lời mời không hợp lệ hoặc đã hết hạn")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo tài khoản")
+	}
+
+	if err := issueSession(c, newUser); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(common.NewResponse(fiber.StatusOK, "Đăng ký thành công", newUser))
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"math"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"strings"
+	"time"
+)
+
+// Failed logins below this count are answered right away, after it every failure doubles the wait before the next try.
+const loginFreeAttempts = 3
+
+func loginThrottleKeys(c *fiber.Ctx, userName string) (string, string) {
+	return "username:" + strings.ToLower(userName), "ip:" + c.IP()
+}
+
+// loginDelay is the time a caller has to wait after the given number of failures, capped at one minute.
+func loginDelay(failures int) time.Duration {
+	if failures < loginFreeAttempts {
+		return 0
+	}
+	return time.Duration(math.Min(math.Pow(2, float64(failures-loginFreeAttempts)), 60)) * time.Second
+}
+
+// checkLoginThrottle rejects the login while one of the keys is locked or still has to wait.
+func checkLoginThrottle(keys ...string) error {
+	var throttles []entity.LoginThrottle
+	if err := common.DBConn.Find(&throttles, "key IN ?", keys).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	now := time.Now()
+	for _, throttle := range throttles {
+		if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+			return fiber.NewError(fiber.StatusTooManyRequests, "Đăng nhập sai quá nhiều lần, vui lòng thử lại sau")
+		}
+
+		if wait := throttle.LastFailedAt.Add(loginDelay(throttle.Failures)).Sub(now); wait > 0 {
+			return fiber.NewError(fiber.StatusTooManyRequests, fmt.Sprintf("Vui lòng thử lại sau %v giây", math.Ceil(wait.Seconds())))
+		}
+	}
+
+	return nil
+}
+
+// recordLoginFailure increases the failure count of the key and locks it once maxAttempts is reached.
+func recordLoginFailure(key string, maxAttempts int) error {
+	lockoutDuration := common.GetEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	now := time.Now()
+
+	var throttle entity.LoginThrottle
+	if err := common.DBConn.First(&throttle, "key = ?", key).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+		}
+		throttle.Key = key
+	}
+
+	// Start counting again once a lockout has passed or the last failure is old enough
+	if (throttle.LockedUntil != nil && now.After(*throttle.LockedUntil)) || now.Sub(throttle.LastFailedAt) > lockoutDuration {
+		throttle.Failures = 0
+		throttle.LockedUntil = nil
+	}
+
+	throttle.Failures++
+	throttle.LastFailedAt = now
+
+	if throttle.Failures >= maxAttempts {
+		lockedUntil := now.Add(lockoutDuration)
+		throttle.LockedUntil = &lockedUntil
+	}
+
+	if err := common.DBConn.Save(&throttle).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	return nil
+}
+
+func recordLoginFailures(c *fiber.Ctx, userName string) error {
+	userNameKey, ipKey := loginThrottleKeys(c, userName)
+
+	if err := recordLoginFailure(userNameKey, common.GetEnvInt("LOGIN_MAX_ATTEMPTS", 10)); err != nil {
+		return err
+	}
+
+	return recordLoginFailure(ipKey, common.GetEnvInt("LOGIN_IP_MAX_ATTEMPTS", 50))
+}
+
+// clearLoginFailures only resets the username, the IP counter keeps running so one valid account cannot unlock an address.
+func clearLoginFailures(c *fiber.Ctx, userName string) error {
+	userNameKey, _ := loginThrottleKeys(c, userName)
+
+	if err := common.DBConn.Where("key = ?", userNameKey).Delete(&entity.LoginThrottle{}).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	return nil
+}
+
+// [GET] /api/users/lockouts
+func UserLockoutGetAll(c *fiber.Ctx) error {
+	var throttles []entity.LoginThrottle
+
+	if err := common.DBConn.Order("locked_until desc").Find(&throttles, "locked_until > ?", time.Now()).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", throttles))
+}
+
+// [DELETE] /api/users/lockouts/:id
+func UserLockoutDeleteById(c *fiber.Ctx) error {
+	throttleId := c.Params("id")
+
+	var throttle entity.LoginThrottle
+	if err := common.DBConn.First(&throttle, "id = ?", throttleId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy khóa đăng nhập")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := common.DBConn.Delete(&throttle).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi mở khóa đăng nhập")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", nil))
+}
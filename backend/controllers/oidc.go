@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"log"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type oidcLogin struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+var invalidUserNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func oidcProvider() (*common.OIDCProvider, error) {
+	if !common.OIDCEnabled() {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Đăng nhập OIDC chưa được bật")
+	}
+
+	provider, err := common.GetOIDCProvider()
+	if err != nil {
+		log.Println("Error discovering OIDC provider:", err)
+		return nil, fiber.NewError(fiber.StatusBadGateway, "Không thể kết nối tới nhà cung cấp định danh")
+	}
+
+	return provider, nil
+}
+
+// oidcUserName picks a free username from the preferred username claim or the local part of the email.
+func oidcUserName(claims *common.OIDCClaims) (string, error) {
+	base := claims.UserName
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	base = invalidUserNameChars.ReplaceAllString(base, "")
+	if len(base) < 3 {
+		base = "user" + base
+	}
+	if len(base) > 25 {
+		base = base[:25]
+	}
+
+	userName := base
+	for i := 0; i < 5; i++ {
+		var existUser entity.User
+		if err := common.DBConn.Unscoped().Select("id").First(&existUser, "user_name = ?", userName).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return userName, nil
+			}
+			return "", err
+		}
+
+		suffix, err := common.GenerateRandToken(2)
+		if err != nil {
+			return "", err
+		}
+		userName = base + suffix
+	}
+
+	return "", errors.New("no free username")
+}
+
+// oidcUser matches the identity provider's email to an account, creating one when OIDC_AUTO_PROVISION allows it.
+func oidcUser(claims *common.OIDCClaims) (*entity.User, error) {
+	var user entity.User
+	if err := common.DBConn.Unscoped().First(&user, "lower(email) = ?", claims.Email).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+		}
+	}
+
+	if user.ID != 0 {
+		if user.DeletedAt.Valid {
+			return nil, fiber.NewError(fiber.StatusForbidden, "Tài khoản đã bị vô hiệu hoá")
+		}
+		return &user, nil
+	}
+
+	if !common.GetEnvBool("OIDC_AUTO_PROVISION", true) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Tài khoản chưa được cấp quyền truy cập hệ thống")
+	}
+
+	userName, err := oidcUserName(claims)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo tài khoản")
+	}
+
+	// The account can only sign in through the identity provider until a password is set with the reset flow
+	randomPassword, err := common.GenerateRandToken(32)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo tài khoản")
+	}
+
+	hashPassword, hashPasswordErr := bcrypt.GenerateFromPassword([]byte(randomPassword), 11)
+	if hashPasswordErr != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo tài khoản")
+	}
+
+	role := common.GetEnvString("OIDC_DEFAULT_ROLE", entity.RoleStudent)
+	if !common.IsValidRole(role) {
+		role = entity.RoleStudent
+	}
+
+	firstName, lastName := claims.FirstName, claims.LastName
+	if firstName == "" {
+		firstName = userName
+	}
+
+	user = entity.User{
+		FirstName: truncate(firstName, 50),
+		LastName:  truncate(lastName, 50),
+		UserName:  userName,
+		Email:     &claims.Email,
+		Password:  string(hashPassword),
+		Role:      role,
+	}
+
+	if err := common.DBConn.Create(&user).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo tài khoản")
+	}
+
+	return &user, nil
+}
+
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) > length {
+		return string(runes[:length])
+	}
+	return value
+}
+
+// [GET] /api/auth/oidc/login
+func AuthOIDCLogin(c *fiber.Ctx) error {
+	provider, err := oidcProvider()
+	if err != nil {
+		return err
+	}
+
+	state, stateErr := common.GenerateRandToken(16)
+	nonce, nonceErr := common.GenerateRandToken(16)
+	codeVerifier, codeVerifierErr := common.GenerateRandToken(32)
+	if stateErr != nil || nonceErr != nil || codeVerifierErr != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo token")
+	}
+
+	loginState := entity.OIDCLoginState{
+		ID:           common.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(common.GetEnvDuration("OIDC_STATE_TTL", 10*time.Minute)),
+	}
+
+	if err := common.DBConn.Where("expires_at < ?", time.Now()).Delete(&entity.OIDCLoginState{}).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := common.DBConn.Create(&loginState).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", oidcLogin{
+		AuthorizationURL: provider.AuthCodeURL(state, nonce, codeVerifier),
+	}))
+}
+
+// [POST] /api/auth/oidc/callback
+func AuthOIDCCallback(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.AuthOIDCCallback](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	provider, err := oidcProvider()
+	if err != nil {
+		return err
+	}
+
+	var loginState entity.OIDCLoginState
+	if err := common.DBConn.First(&loginState, "id = ?", common.HashToken(bodyData.State)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Phiên đăng nhập OIDC không hợp lệ hoặc đã hết hạn")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	// Deleting the state before the exchange keeps every state single-use
+	deleteResult := common.DBConn.Delete(&entity.OIDCLoginState{}, "id = ?", loginState.ID)
+	if deleteResult.Error != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	if deleteResult.RowsAffected == 0 || time.Now().After(loginState.ExpiresAt) {
+		return fiber.NewError(fiber.StatusBadRequest, "Phiên đăng nhập OIDC không hợp lệ hoặc đã hết hạn")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	rawIDToken, err := provider.Exchange(ctx, bodyData.Code, loginState.CodeVerifier)
+	if err != nil {
+		log.Println("Error exchanging OIDC code:", err)
+		return fiber.NewError(fiber.StatusUnauthorized, "Không thể xác thực với nhà cung cấp định danh")
+	}
+
+	claims, err := provider.VerifyIDToken(rawIDToken, loginState.Nonce)
+	if err != nil {
+		log.Println("Error verifying OIDC id token:", err)
+		return fiber.NewError(fiber.StatusUnauthorized, "Không thể xác thực với nhà cung cấp định danh")
+	}
+
+	userRecord, err := oidcUser(claims)
+	if err != nil {
+		return err
+	}
+
+	if userRecord.TOTPEnabled || twoFactorRequired(*userRecord) {
+		challengeToken, err := createChallengeJWT(userRecord.ID)
+
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		}
+
+		return c.JSON(common.NewResponse(fiber.StatusOK, "Vui lòng xác thực hai bước", twoFactorChallenge{
+			ChallengeToken: challengeToken,
+			TOTPEnabled:    userRecord.TOTPEnabled,
+		}))
+	}
+
+	if err := issueSession(c, *userRecord); err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Đăng nhập thành công", userRecord))
+}
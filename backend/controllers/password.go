@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"log"
+	"net/url"
+	"os"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"time"
+)
+
+// userEmail falls back to the email of the linked student or instructor when the account has none.
+func userEmail(user entity.User) (string, error) {
+	if user.Email != nil && *user.Email != "" {
+		return *user.Email, nil
+	}
+
+	if user.StudentID != nil {
+		var student entity.Student
+		if err := common.DBConn.Select("email").First(&student, "id = ?", *user.StudentID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+		if student.Email != "" {
+			return student.Email, nil
+		}
+	}
+
+	if user.InstructorID != nil {
+		var instructor entity.Instructor
+		if err := common.DBConn.Select("email").First(&instructor, "id = ?", *user.InstructorID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+		return instructor.Email, nil
+	}
+
+	return "", nil
+}
+
+// [POST] /api/auth/password/change
+func AuthPasswordChange(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.AuthPasswordChange](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(bodyData.CurrentPassword)); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Mật khẩu hiện tại không đúng")
+	}
+
+	if bodyData.NewPassword == bodyData.CurrentPassword {
+		return fiber.NewError(fiber.StatusBadRequest, "Mật khẩu mới phải khác mật khẩu hiện tại")
+	}
+
+	if err := common.ValidatePassword(bodyData.NewPassword); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	hashPassword, hashPasswordErr := bcrypt.GenerateFromPassword([]byte(bodyData.NewPassword), 11)
+
+	if hashPasswordErr != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi đổi mật khẩu")
+	}
+
+	currentSessionId, _ := c.Locals("currentSessionId").(string)
+
+	// Every other session is logged out, the one that changed the password stays valid
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password", string(hashPassword)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.UserSession{}).Where("user_id = ? and id <> ? and revoked_at IS NULL", user.ID, currentSessionId).Update("revoked_at", time.Now()).Error
+	}); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi đổi mật khẩu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Đổi mật khẩu thành công", nil))
+}
+
+// [POST] /api/auth/password/forgot
+func AuthPasswordForgot(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.AuthPasswordForgot](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	// The answer is the same whether the account exists or not
+	response := common.NewResponse(fiber.StatusOK, "Nếu tài khoản tồn tại, email đặt lại mật khẩu đã được gửi", nil)
+
+	var user entity.User
+	if err := common.DBConn.First(&user, "user_name = ?", bodyData.UserName).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(response)
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	email, err := userEmail(user)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	if email == "" {
+		return c.JSON(response)
+	}
+
+	token, err := common.GenerateRandToken(32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo token")
+	}
+
+	ttl := common.GetEnvDuration("PASSWORD_RESET_TTL", 30*time.Minute)
+	newToken := entity.PasswordResetToken{
+		TokenHash: common.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+		UserID:    user.ID,
+	}
+
+	// Requesting a new token invalidates the older ones
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.PasswordResetToken{}).Where("user_id = ? and used_at IS NULL", user.ID).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&newToken).Error
+	}); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo token")
+	}
+
+	link := os.Getenv("CLIENT_URL") + "/reset-password?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Xin chào %s %s,\n\nVui lòng truy cập liên kết sau để đặt lại mật khẩu, liên kết có hiệu lực trong %v:\n%s\n\nNếu bạn không yêu cầu đặt lại mật khẩu, hãy bỏ qua email này.", user.FirstName, user.LastName, ttl, link)
+
+	// A failed send answers like an unknown account, so the response never tells which accounts exist
+	if err := common.Mailer.Send(email, "Đặt lại mật khẩu", body); err != nil {
+		log.Println("Error sending password reset mail:", err)
+	}
+
+	return c.JSON(response)
+}
+
+// [POST] /api/auth/password/reset
+func AuthPasswordReset(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.AuthPasswordReset](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := common.ValidatePassword(bodyData.NewPassword); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var resetToken entity.PasswordResetToken
+	if err := common.DBConn.First(&resetToken, "token_hash = ? and used_at IS NULL and expires_at > ?", common.HashToken(bodyData.Token), time.Now()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Token không hợp lệ hoặc đã hết hạn")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	var user entity.User
+	if err := common.DBConn.First(&user, "id = ?", resetToken.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Token không hợp lệ hoặc đã hết hạn")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	hashPassword, hashPasswordErr := bcrypt.GenerateFromPassword([]byte(bodyData.NewPassword), 11)
+
+	if hashPasswordErr != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi đặt lại mật khẩu")
+	}
+
+	errTokenUsed := errors.New("token used")
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		// Marking the token used and checking the affected rows keeps it single-use under concurrent requests
+		result := tx.Model(&entity.PasswordResetToken{}).Where("id = ? and used_at IS NULL", resetToken.ID).Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errTokenUsed
+		}
+
+		if err := tx.Model(&user).Update("password", string(hashPassword)).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&entity.UserSession{}).Where("user_id = ? and revoked_at IS NULL", user.ID).Update("revoked_at", time.Now()).Error
+	}); err != nil {
+		if errors.Is(err, errTokenUsed) {
+			return fiber.NewError(fiber.StatusBadRequest, "Token không hợp lệ hoặc đã hết hạn")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi đặt lại mật khẩu")
+	}
+
+	if err := clearLoginFailures(c, user.UserName); err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Đặt lại mật khẩu thành công", nil))
+}
@@ -0,0 +1,727 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"path/filepath"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const personImportMaxRows = 2000
+
+// importColumn is a field of the imported rows, found in the file by its mapped header, its field name or its label.
+type importColumn struct {
+	field    string
+	label    string
+	optional bool
+}
+
+var studentImportColumns = []importColumn{
+	{field: "first_name", label: "Họ"},
+	{field: "last_name", label: "Tên"},
+	{field: "email", label: "Email"},
+	{field: "address", label: "Địa chỉ"},
+	{field: "birth_day", label: "Ngày sinh"},
+	{field: "phone", label: "Số điện thoại"},
+	{field: "gender", label: "Giới tính", optional: true},
+	{field: "academic_year", label: "Khóa"},
+	{field: "class_id", label: "Mã lớp"},
+	{field: "department_id", label: "Mã khoa"},
+}
+
+var instructorImportColumns = []importColumn{
+	{field: "first_name", label: "Họ"},
+	{field: "last_name", label: "Tên"},
+	{field: "email", label: "Email"},
+	{field: "address", label: "Địa chỉ"},
+	{field: "degree", label: "Học vị"},
+	{field: "birth_day", label: "Ngày sinh"},
+	{field: "phone", label: "Số điện thoại"},
+	{field: "gender", label: "Giới tính", optional: true},
+	{field: "department_id", label: "Mã khoa"},
+}
+
+var importDateLayouts = []string{"2006-01-02", "02/01/2006", "2/1/2006", "01-02-06", time.RFC3339}
+
+type personImportResult struct {
+	Row    int      `json:"row"`
+	ID     string   `json:"id,omitempty"`
+	Email  string   `json:"email"`
+	Errors []string `json:"errors,omitempty"`
+	Data   any      `json:"data,omitempty"`
+}
+
+type personImportReport struct {
+	Preview  bool                 `json:"preview"`
+	Valid    bool                 `json:"valid"`
+	Rows     int                  `json:"rows"`
+	Imported int                  `json:"imported"`
+	Mapping  map[string]string    `json:"mapping"`
+	Results  []personImportResult `json:"results"`
+}
+
+func (report *personImportReport) rowError(idx int, message string) {
+	report.Results[idx].Errors = append(report.Results[idx].Errors, message)
+}
+
+// readImportRows reads the uploaded file, a CSV file or the first sheet of an Excel file, header row included.
+func readImportRows(c *fiber.Ctx) ([][]string, error) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Vui lòng chọn file excel hoặc csv")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Không đọc được file")
+	}
+	defer file.Close()
+
+	var rows [][]string
+	if strings.EqualFold(filepath.Ext(fileHeader.Filename), ".csv") {
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		if rows, err = reader.ReadAll(); err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "File csv không hợp lệ")
+		}
+		if len(rows) > 0 && len(rows[0]) > 0 {
+			rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+		}
+	} else {
+		f, err := excelize.OpenReader(file)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "File excel không hợp lệ")
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				fmt.Println(err)
+			}
+		}()
+
+		if rows, err = f.GetRows(f.GetSheetList()[0]); err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "File excel không hợp lệ")
+		}
+	}
+
+	if len(rows) < 2 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "File không có dữ liệu")
+	}
+	if len(rows)-1 > personImportMaxRows {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Mỗi lần chỉ được nhập tối đa %d dòng", personImportMaxRows))
+	}
+
+	return rows, nil
+}
+
+// mapImportColumns finds the column of every field. The optional mapping form value maps fields to headers of the
+// file, other fields are found by their field name or label.
+func mapImportColumns(c *fiber.Ctx, header []string, columns []importColumn) (map[string]int, map[string]string, error) {
+	mapping := make(map[string]string)
+	if value := c.FormValue("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Mapping cột không hợp lệ")
+		}
+	}
+
+	known := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		known[column.field] = true
+	}
+	for field := range mapping {
+		if !known[field] {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "Không có trường "+field)
+		}
+	}
+
+	findHeader := func(names ...string) int {
+		for idx, text := range header {
+			for _, name := range names {
+				if strings.EqualFold(strings.TrimSpace(text), strings.TrimSpace(name)) {
+					return idx
+				}
+			}
+		}
+		return -1
+	}
+
+	indexes := make(map[string]int, len(columns))
+	used := make(map[string]string, len(columns))
+	missing := make([]string, 0)
+	for _, column := range columns {
+		idx := -1
+		if name, ok := mapping[column.field]; ok {
+			if idx = findHeader(name); idx < 0 {
+				return nil, nil, fiber.NewError(fiber.StatusBadRequest, "File không có cột "+name)
+			}
+		} else {
+			idx = findHeader(column.field, column.label)
+		}
+
+		if idx < 0 {
+			if !column.optional {
+				missing = append(missing, column.label)
+			}
+			continue
+		}
+		indexes[column.field] = idx
+		used[column.field] = strings.TrimSpace(header[idx])
+	}
+
+	if len(missing) > 0 {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "File thiếu cột: "+strings.Join(missing, ", "))
+	}
+
+	return indexes, used, nil
+}
+
+func importValue(row []string, indexes map[string]int, field string) string {
+	idx, ok := indexes[field]
+	if !ok || idx >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[idx])
+}
+
+func importRowEmpty(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func parseImportDate(text string) (time.Time, error) {
+	for _, layout := range importDateLayouts {
+		if date, err := time.Parse(layout, text); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, errors.New("Ngày sinh không hợp lệ, dùng định dạng YYYY-MM-DD hoặc DD/MM/YYYY")
+}
+
+// parseImportGender reads the gender the way the forms store it, true for female.
+func parseImportGender(text string) (bool, error) {
+	switch strings.ToLower(text) {
+	case "", "nam", "false", "0":
+		return false, nil
+	case "nữ", "nu", "true", "1":
+		return true, nil
+	}
+	return false, errors.New("Giới tính phải là Nam hoặc Nữ")
+}
+
+func parseImportUint(text string, message string) (uint, error) {
+	if text == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return 0, errors.New(message)
+	}
+	return uint(value), nil
+}
+
+// checkImportContacts reports the rows whose email or phone repeats an earlier row of the file or a record of the
+// table of model.
+func checkImportContacts(report *personImportReport, model any, emails []string, phones []string) error {
+	lowerEmails := make([]string, 0, len(emails))
+	for _, email := range emails {
+		lowerEmails = append(lowerEmails, strings.ToLower(email))
+	}
+
+	var existing []struct {
+		Email string
+		Phone string
+	}
+	if err := common.DBConn.Model(model).Select("email", "phone").
+		Where("LOWER(email) IN ? OR phone IN ?", lowerEmails, phones).Find(&existing).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	existingEmails := make(map[string]bool, len(existing))
+	existingPhones := make(map[string]bool, len(existing))
+	for _, record := range existing {
+		existingEmails[strings.ToLower(record.Email)] = true
+		existingPhones[record.Phone] = true
+	}
+
+	fileEmails := make(map[string]int)
+	filePhones := make(map[string]int)
+	for idx := range report.Results {
+		email, phone := lowerEmails[idx], phones[idx]
+
+		if email != "" {
+			if row, ok := fileEmails[email]; ok {
+				report.rowError(idx, fmt.Sprintf("Email trùng với dòng %d", row))
+			} else {
+				fileEmails[email] = report.Results[idx].Row
+			}
+			if existingEmails[email] {
+				report.rowError(idx, "Email đã tồn tại")
+			}
+		}
+
+		if phone != "" {
+			if row, ok := filePhones[phone]; ok {
+				report.rowError(idx, fmt.Sprintf("Số điện thoại trùng với dòng %d", row))
+			} else {
+				filePhones[phone] = report.Results[idx].Row
+			}
+			if existingPhones[phone] {
+				report.rowError(idx, "Số điện thoại đã tồn tại")
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkImportDepartments reports the rows of departments that do not exist or are out of the scope of the user.
+func checkImportDepartments(c *fiber.Ctx, report *personImportReport, departmentsId []uint) error {
+	var existing []uint
+	if err := common.DBConn.Model(&entity.Department{}).Where("id IN ?", departmentsId).Pluck("id", &existing).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	found := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		found[id] = true
+	}
+
+	for idx, departmentId := range departmentsId {
+		if departmentId == 0 {
+			continue
+		}
+		if !found[departmentId] {
+			report.rowError(idx, "Không tìm thấy khoa")
+			continue
+		}
+		if err := checkDepartmentScope(c, departmentId); err != nil {
+			report.rowError(idx, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// uniqueImportIds regenerates the generated ids that collide with each other or with the table of model, the random
+// part of the ids is short enough for a cohort to hit it.
+func uniqueImportIds(tx *gorm.DB, model any, ids []string, generate func(idx int) string) error {
+	for round := 0; round < 20; round++ {
+		var existing []string
+		if err := tx.Model(model).Where("id IN ?", ids).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+
+		taken := make(map[string]bool, len(existing))
+		for _, id := range existing {
+			taken[id] = true
+		}
+
+		collisions := 0
+		for idx, id := range ids {
+			if taken[id] {
+				ids[idx] = generate(idx)
+				collisions++
+				continue
+			}
+			taken[id] = true
+		}
+		if collisions == 0 {
+			return nil
+		}
+	}
+
+	return errors.New("could not generate unique ids")
+}
+
+func (report *personImportReport) finish() {
+	report.Valid = true
+	for _, result := range report.Results {
+		if len(result.Errors) > 0 {
+			report.Valid = false
+		}
+	}
+}
+
+// prepareStudentImport reads and checks the students of the uploaded file with the rules of StudentCreate.
+func prepareStudentImport(c *fiber.Ctx) (*personImportReport, []req.StudentCreate, error) {
+	rows, err := readImportRows(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	indexes, mapping, err := mapImportColumns(c, rows[0], studentImportColumns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &personImportReport{Mapping: mapping, Results: make([]personImportResult, 0, len(rows)-1)}
+	students := make([]req.StudentCreate, 0, len(rows)-1)
+	today := time.Now()
+
+	for idx, row := range rows[1:] {
+		if importRowEmpty(row) {
+			continue
+		}
+
+		student := req.StudentCreate{
+			FirstName: importValue(row, indexes, "first_name"),
+			LastName:  importValue(row, indexes, "last_name"),
+			Email:     importValue(row, indexes, "email"),
+			Address:   importValue(row, indexes, "address"),
+			Phone:     importValue(row, indexes, "phone"),
+			ClassID:   importValue(row, indexes, "class_id"),
+		}
+		result := personImportResult{Row: idx + 2, Email: student.Email, Errors: make([]string, 0)}
+
+		if text := importValue(row, indexes, "birth_day"); text != "" {
+			if student.BirthDay, err = parseImportDate(text); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+		if student.Gender, err = parseImportGender(importValue(row, indexes, "gender")); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		if text := importValue(row, indexes, "academic_year"); text != "" {
+			if student.AcademicYear, err = strconv.Atoi(text); err != nil {
+				result.Errors = append(result.Errors, "Khóa học phải là số")
+			}
+		}
+		departmentId, err := parseImportUint(importValue(row, indexes, "department_id"), "Mã khoa phải là số")
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		student.DepartmentID = departmentId
+
+		if len(result.Errors) == 0 {
+			if err := common.ValidateStruct(student); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+		if student.BirthDay.After(today) {
+			result.Errors = append(result.Errors, "Ngày sinh không hợp lệ")
+		}
+		if student.AcademicYear > today.Year() {
+			result.Errors = append(result.Errors, "Năm học không hợp lệ")
+		}
+
+		result.Data = student
+		report.Results = append(report.Results, result)
+		students = append(students, student)
+	}
+
+	if len(students) == 0 {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "File không có dữ liệu")
+	}
+	report.Rows = len(students)
+
+	emails := make([]string, 0, len(students))
+	phones := make([]string, 0, len(students))
+	departmentsId := make([]uint, 0, len(students))
+	classesId := make([]string, 0, len(students))
+	for _, student := range students {
+		emails = append(emails, student.Email)
+		phones = append(phones, student.Phone)
+		departmentsId = append(departmentsId, student.DepartmentID)
+		classesId = append(classesId, student.ClassID)
+	}
+
+	if err := checkImportContacts(report, &entity.Student{}, emails, phones); err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkImportDepartments(c, report, departmentsId); err != nil {
+		return nil, nil, err
+	}
+
+	var classes []entity.Class
+	if err := common.DBConn.Find(&classes, "id IN ?", classesId).Error; err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	var counts []struct {
+		ClassID string
+		Count   int
+	}
+	if err := common.DBConn.Model(&entity.Student{}).Select("class_id, COUNT(*) AS count").
+		Where("class_id IN ?", classesId).Group("class_id").Scan(&counts).Error; err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	classesById := make(map[string]entity.Class, len(classes))
+	for _, class := range classes {
+		classesById[class.ID] = class
+	}
+	seats := make(map[string]int, len(classes))
+	for _, class := range classes {
+		seats[class.ID] = class.MaxStudents
+	}
+	for _, count := range counts {
+		seats[count.ClassID] -= count.Count
+	}
+
+	for idx, student := range students {
+		if student.ClassID == "" {
+			continue
+		}
+
+		class, ok := classesById[student.ClassID]
+		if !ok {
+			report.rowError(idx, "Không tìm thấy lớp")
+			continue
+		}
+		if class.HostInstructorID == "" {
+			report.rowError(idx, "Lớp chưa có giảng viên chủ nhiệm")
+		}
+		if class.DepartmentID != student.DepartmentID {
+			report.rowError(idx, "Khoa của lớp không trùng với khoa của sinh viên")
+		}
+		if class.AcademicYear != student.AcademicYear {
+			report.rowError(idx, "Khoá học của lớp không trùng với khoá học của sinh viên")
+		}
+
+		// The rows of the file fill the remaining seats of the class in order
+		if seats[class.ID] <= 0 {
+			report.rowError(idx, "Lớp đã đủ số lượng sinh viên")
+			continue
+		}
+		seats[class.ID]--
+	}
+
+	report.finish()
+	return report, students, nil
+}
+
+// prepareInstructorImport reads and checks the instructors of the uploaded file with the rules of InstructorCreate.
+func prepareInstructorImport(c *fiber.Ctx) (*personImportReport, []req.InstructorCreate, error) {
+	rows, err := readImportRows(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	indexes, mapping, err := mapImportColumns(c, rows[0], instructorImportColumns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &personImportReport{Mapping: mapping, Results: make([]personImportResult, 0, len(rows)-1)}
+	instructors := make([]req.InstructorCreate, 0, len(rows)-1)
+	today := time.Now()
+
+	for idx, row := range rows[1:] {
+		if importRowEmpty(row) {
+			continue
+		}
+
+		instructor := req.InstructorCreate{
+			FirstName: importValue(row, indexes, "first_name"),
+			LastName:  importValue(row, indexes, "last_name"),
+			Email:     importValue(row, indexes, "email"),
+			Address:   importValue(row, indexes, "address"),
+			Degree:    importValue(row, indexes, "degree"),
+			Phone:     importValue(row, indexes, "phone"),
+		}
+		result := personImportResult{Row: idx + 2, Email: instructor.Email, Errors: make([]string, 0)}
+
+		if text := importValue(row, indexes, "birth_day"); text != "" {
+			if instructor.BirthDay, err = parseImportDate(text); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+		if instructor.Gender, err = parseImportGender(importValue(row, indexes, "gender")); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		departmentId, err := parseImportUint(importValue(row, indexes, "department_id"), "Mã khoa phải là số")
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		instructor.DepartmentID = departmentId
+
+		if len(result.Errors) == 0 {
+			if err := common.ValidateStruct(instructor); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+		if instructor.BirthDay.After(today) {
+			result.Errors = append(result.Errors, "Ngày sinh không hợp lệ")
+		}
+
+		result.Data = instructor
+		report.Results = append(report.Results, result)
+		instructors = append(instructors, instructor)
+	}
+
+	if len(instructors) == 0 {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "File không có dữ liệu")
+	}
+	report.Rows = len(instructors)
+
+	emails := make([]string, 0, len(instructors))
+	phones := make([]string, 0, len(instructors))
+	departmentsId := make([]uint, 0, len(instructors))
+	for _, instructor := range instructors {
+		emails = append(emails, instructor.Email)
+		phones = append(phones, instructor.Phone)
+		departmentsId = append(departmentsId, instructor.DepartmentID)
+	}
+
+	if err := checkImportContacts(report, &entity.Instructor{}, emails, phones); err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkImportDepartments(c, report, departmentsId); err != nil {
+		return nil, nil, err
+	}
+
+	report.finish()
+	return report, instructors, nil
+}
+
+func personImportInvalid(c *fiber.Ctx, report *personImportReport) error {
+	invalid := 0
+	for _, result := range report.Results {
+		if len(result.Errors) > 0 {
+			invalid++
+		}
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(common.NewResponse(fiber.StatusBadRequest,
+		fmt.Sprintf("Có %d dòng không hợp lệ, chưa có dữ liệu nào được lưu", invalid), report))
+}
+
+// [POST] /api/students/import/preview
+func StudentImportPreview(c *fiber.Ctx) error {
+	report, _, err := prepareStudentImport(c)
+	if err != nil {
+		return err
+	}
+
+	report.Preview = true
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", report))
+}
+
+// [POST] /api/students/import
+func StudentImport(c *fiber.Ctx) error {
+	report, bodyData, err := prepareStudentImport(c)
+	if err != nil {
+		return err
+	}
+
+	if !report.Valid {
+		return personImportInvalid(c, report)
+	}
+
+	newStudents := make([]entity.Student, 0, len(bodyData))
+	ids := make([]string, 0, len(bodyData))
+	for _, student := range bodyData {
+		newStudents = append(newStudents, entity.Student{
+			FirstName:    student.FirstName,
+			LastName:     student.LastName,
+			Email:        student.Email,
+			Address:      student.Address,
+			BirthDay:     student.BirthDay,
+			Phone:        student.Phone,
+			Gender:       student.Gender,
+			AcademicYear: student.AcademicYear,
+			ClassID:      student.ClassID,
+			DepartmentID: student.DepartmentID,
+		})
+		ids = append(ids, generateStudentId(student.DepartmentID, student.AcademicYear))
+	}
+
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := uniqueImportIds(tx, &entity.Student{}, ids, func(idx int) string {
+			return generateStudentId(bodyData[idx].DepartmentID, bodyData[idx].AcademicYear)
+		}); err != nil {
+			return err
+		}
+		for idx := range newStudents {
+			newStudents[idx].ID = ids[idx]
+		}
+		return tx.CreateInBatches(&newStudents, 100).Error
+	}); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo sinh viên")
+	}
+
+	for idx := range report.Results {
+		report.Results[idx].ID = newStudents[idx].ID
+		report.Results[idx].Data = newStudents[idx]
+	}
+	report.Imported = len(newStudents)
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", report))
+}
+
+// [POST] /api/instructors/import/preview
+func InstructorImportPreview(c *fiber.Ctx) error {
+	report, _, err := prepareInstructorImport(c)
+	if err != nil {
+		return err
+	}
+
+	report.Preview = true
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", report))
+}
+
+// [POST] /api/instructors/import
+func InstructorImport(c *fiber.Ctx) error {
+	report, bodyData, err := prepareInstructorImport(c)
+	if err != nil {
+		return err
+	}
+
+	if !report.Valid {
+		return personImportInvalid(c, report)
+	}
+
+	newInstructors := make([]entity.Instructor, 0, len(bodyData))
+	ids := make([]string, 0, len(bodyData))
+	for _, instructor := range bodyData {
+		newInstructors = append(newInstructors, entity.Instructor{
+			FirstName:    instructor.FirstName,
+			LastName:     instructor.LastName,
+			Email:        instructor.Email,
+			Address:      instructor.Address,
+			Degree:       instructor.Degree,
+			BirthDay:     instructor.BirthDay,
+			Phone:        instructor.Phone,
+			Gender:       instructor.Gender,
+			DepartmentID: instructor.DepartmentID,
+		})
+		ids = append(ids, generateInstructorID(instructor.DepartmentID))
+	}
+
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := uniqueImportIds(tx, &entity.Instructor{}, ids, func(idx int) string {
+			return generateInstructorID(bodyData[idx].DepartmentID)
+		}); err != nil {
+			return err
+		}
+		for idx := range newInstructors {
+			newInstructors[idx].ID = ids[idx]
+		}
+		return tx.CreateInBatches(&newInstructors, 100).Error
+	}); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo giảng viên")
+	}
+
+	for idx := range report.Results {
+		report.Results[idx].ID = newInstructors[idx].ID
+		report.Results[idx].Data = newInstructors[idx]
+	}
+	report.Imported = len(newInstructors)
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", report))
+}
@@ -10,11 +10,25 @@ import (
 	"qldiemsv/models/req"
 )
 
+// checkRegistrationUngraded refuses with the message once a grade was recorded for the registration.
+func checkRegistrationUngraded(registrationId uint, message string) error {
+	var count int64
+	if err := common.DBConn.Model(&entity.Grade{}).Where("registration_id = ?", registrationId).Count(&count).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if count > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, message)
+	}
+
+	return nil
+}
+
 // [GET] /api/registrations
 func RegistrationGetAll(c *fiber.Ctx) error {
 	var registrations []entity.StudentRegistration
 
-	if err := common.DBConn.Find(&registrations).Error; err != nil {
+	if err := common.DBConn.Scopes(scopeSubjectDepartment(c, "subject_id"), scopeTerm(c)).Find(&registrations).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -39,7 +53,7 @@ func RegistrationGetAllStudentByFullName(c *fiber.Ctx) error {
 	}
 
 	var registrations []entity.StudentRegistration
-	if err := common.DBConn.Where("student_id IN ?", studentsId).Find(&registrations).Error; err != nil {
+	if err := common.DBConn.Where("student_id IN ?", studentsId).Scopes(scopeSubjectDepartment(c, "subject_id"), scopeTerm(c)).Find(&registrations).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -53,6 +67,10 @@ func RegistrationGetAllStudentByFullName(c *fiber.Ctx) error {
 func RegistrationGetAllByDepartmentId(c *fiber.Ctx) error {
 	departmentId := c.Params("id")
 
+	if err := checkDepartmentScopeById(c, departmentId); err != nil {
+		return err
+	}
+
 	var subjectsId []string
 
 	if err := common.DBConn.Model(&entity.Subject{}).Select("id").Where("department_id = ?", departmentId).Find(&subjectsId).Error; err != nil {
@@ -60,7 +78,7 @@ func RegistrationGetAllByDepartmentId(c *fiber.Ctx) error {
 	}
 
 	var registrations []entity.StudentRegistration
-	if err := common.DBConn.Where("subject_id IN ?", subjectsId).Find(&registrations).Error; err != nil {
+	if err := common.DBConn.Where("subject_id IN ?", subjectsId).Scopes(scopeTerm(c)).Find(&registrations).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -85,6 +103,10 @@ func RegistrationCreate(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, subject.DepartmentID); err != nil {
+		return err
+	}
+
 	var student entity.Student
 	if err := common.DBConn.First(&student, "id = ?", bodyData.StudentID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -97,8 +119,13 @@ func RegistrationCreate(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Sinh viên không thuộc khoa của môn học")
 	}
 
+	term, err := openTerm(bodyData.TermID)
+	if err != nil {
+		return err
+	}
+
 	var registration entity.StudentRegistration
-	if err := common.DBConn.First(&registration, "subject_id = ? AND student_id = ?", subject.ID, student.ID).Error; err != nil {
+	if err := common.DBConn.First(&registration, "subject_id = ? AND student_id = ? AND term_id = ?", subject.ID, student.ID, term.ID).Error; err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Lỗi khi truy vấn cơ sở dữ liệu")
 		}
@@ -111,6 +138,7 @@ func RegistrationCreate(c *fiber.Ctx) error {
 	newRegistration := entity.StudentRegistration{
 		SubjectID: bodyData.SubjectID,
 		StudentID: bodyData.StudentID,
+		TermID:    &term.ID,
 	}
 
 	if err := common.DBConn.Create(&newRegistration).Error; err != nil {
@@ -136,6 +164,10 @@ func RegistrationUpdateById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkSubjectScope(c, registration.SubjectID); err != nil {
+		return err
+	}
+
 	var subject entity.Subject
 	if err := common.DBConn.First(&subject, "id = ?", bodyData.SubjectID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -144,6 +176,10 @@ func RegistrationUpdateById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, subject.DepartmentID); err != nil {
+		return err
+	}
+
 	var student entity.Student
 	if err := common.DBConn.First(&student, "id = ?", bodyData.StudentID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -156,8 +192,20 @@ func RegistrationUpdateById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Sinh viên không thuộc khoa của môn học")
 	}
 
+	termId, err := updatedTerm(registration.TermID, bodyData.TermID)
+	if err != nil {
+		return err
+	}
+
+	// The grade of a registration belongs to its subject, student and term
+	if subject.ID != registration.SubjectID || student.ID != registration.StudentID || termId != registration.TermID {
+		if err := checkRegistrationUngraded(registration.ID, "Không thể đổi môn học, sinh viên hoặc học kỳ của đăng ký đã có điểm"); err != nil {
+			return err
+		}
+	}
+
 	var existRegistration entity.StudentRegistration
-	if err := common.DBConn.First(&existRegistration, "subject_id = ? AND student_id = ?", subject.ID, student.ID).Error; err != nil {
+	if err := common.DBConn.Scopes(scopeTermId(termId)).First(&existRegistration, "subject_id = ? AND student_id = ? AND id <> ?", subject.ID, student.ID, registration.ID).Error; err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Lỗi khi truy vấn cơ sở dữ liệu")
 		}
@@ -169,6 +217,7 @@ func RegistrationUpdateById(c *fiber.Ctx) error {
 
 	registration.SubjectID = subject.ID
 	registration.StudentID = student.ID
+	registration.TermID = termId
 
 	if err := common.DBConn.Save(&registration).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật đăng ký")
@@ -188,6 +237,14 @@ func RegistrationDeleteById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkSubjectScope(c, registration.SubjectID); err != nil {
+		return err
+	}
+
+	if err := checkRegistrationUngraded(registration.ID, "Không thể xóa đăng ký đã có điểm"); err != nil {
+		return err
+	}
+
 	if err := common.DBConn.Delete(&registration).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa đăng ký")
 	}
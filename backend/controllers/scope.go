@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"strconv"
+)
+
+// departmentScope returns the departments the current user is limited to, scoped is false when the user is unrestricted.
+func departmentScope(c *fiber.Ctx) (departmentIds []uint, scoped bool) {
+	scoped, _ = c.Locals("departmentScoped").(bool)
+	if !scoped {
+		return nil, false
+	}
+	departmentIds, _ = c.Locals("departmentScope").([]uint)
+	return departmentIds, true
+}
+
+func checkDepartmentScope(c *fiber.Ctx, departmentId uint) error {
+	departmentIds, scoped := departmentScope(c)
+	if !scoped {
+		return nil
+	}
+
+	for _, id := range departmentIds {
+		if id == departmentId {
+			return nil
+		}
+	}
+
+	return fiber.NewError(fiber.StatusForbidden, "Bạn không có quyền truy cập dữ liệu của khoa này")
+}
+
+func checkDepartmentScopeById(c *fiber.Ctx, departmentId string) error {
+	id, err := strconv.ParseUint(departmentId, 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Mã khoa không hợp lệ")
+	}
+
+	return checkDepartmentScope(c, uint(id))
+}
+
+// checkSubjectScope enforces the department scope through the department of the subject.
+func checkSubjectScope(c *fiber.Ctx, subjectId string) error {
+	if _, scoped := departmentScope(c); !scoped {
+		return nil
+	}
+
+	var subject entity.Subject
+	if err := common.DBConn.Select("department_id").First(&subject, "id = ?", subjectId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy môn học")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return checkDepartmentScope(c, subject.DepartmentID)
+}
+
+// scopeDepartment limits a query to the rows whose column references a department in scope.
+func scopeDepartment(c *fiber.Ctx, column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		departmentIds, scoped := departmentScope(c)
+		if !scoped {
+			return db
+		}
+		if len(departmentIds) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(column+" IN ?", departmentIds)
+	}
+}
+
+// scopeSubjectDepartment limits a query to the rows whose subject column references a subject in scope.
+func scopeSubjectDepartment(c *fiber.Ctx, column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		departmentIds, scoped := departmentScope(c)
+		if !scoped {
+			return db
+		}
+		if len(departmentIds) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(column+" IN (?)", common.DBConn.Model(&entity.Subject{}).Select("id").Where("department_id IN ?", departmentIds))
+	}
+}
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+)
+
+type meStudentGrade struct {
+	entity.Grade
+	SubjectName string `json:"subject_name"`
+	Credits     int8   `json:"credits"`
+	ResultText  string `json:"result_text"`
+}
+
+type meStudentClass struct {
+	Class              entity.Class      `json:"class"`
+	Department         entity.Department `json:"department"`
+	HostInstructorName string            `json:"host_instructor_name"`
+}
+
+// currentStudent loads the student linked to the current user, every /api/me/student route is limited to it.
+func currentStudent(c *fiber.Ctx) (*entity.Student, error) {
+	user, err := currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.StudentID == nil {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Tài khoản chưa được liên kết với sinh viên")
+	}
+
+	var student entity.Student
+	if err := common.DBConn.First(&student, "id = ?", *user.StudentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy sinh viên")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return &student, nil
+}
+
+// [GET] /api/me/student
+func MeStudentGet(c *fiber.Ctx) error {
+	student, err := currentStudent(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", student))
+}
+
+// [GET] /api/me/student/grades
+func MeStudentGradeGetAll(c *fiber.Ctx) error {
+	student, err := currentStudent(c)
+	if err != nil {
+		return err
+	}
+
+	var grades []entity.Grade
+	if err := common.DBConn.Scopes(scopeTerm(c), scopePublishedGrades).Preload("Components").Find(&grades, "student_id = ?", student.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	subjectsId := make([]string, 0, len(grades))
+	for _, grade := range grades {
+		subjectsId = append(subjectsId, grade.SubjectID)
+	}
+
+	var subjects []entity.Subject
+	if err := common.DBConn.Preload("Components", preloadAssessmentComponents).Find(&subjects, "id IN ?", subjectsId).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	scales, err := subjectGradingScales(subjects)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	subjectsById := make(map[string]entity.Subject, len(subjects))
+	for _, subject := range subjects {
+		subjectsById[subject.ID] = subject
+	}
+
+	result := make([]meStudentGrade, 0, len(grades))
+	for _, grade := range grades {
+		subject := subjectsById[grade.SubjectID]
+		common.ApplyGradeResult(&grade, subject, scales[subject.ID])
+		result = append(result, meStudentGrade{
+			Grade:       grade,
+			SubjectName: subject.Name,
+			Credits:     subject.Credits,
+			ResultText:  common.GradeResultText(grade),
+		})
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", result))
+}
+
+// [GET] /api/me/student/registrations
+func MeStudentRegistrationGetAll(c *fiber.Ctx) error {
+	student, err := currentStudent(c)
+	if err != nil {
+		return err
+	}
+
+	var registrations []entity.StudentRegistration
+	if err := common.DBConn.Scopes(scopeTerm(c)).Find(&registrations, "student_id = ?", student.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", registrations))
+}
+
+// [GET] /api/me/student/class
+func MeStudentClassGet(c *fiber.Ctx) error {
+	student, err := currentStudent(c)
+	if err != nil {
+		return err
+	}
+
+	// Classmates are not preloaded, a student only sees the class itself
+	var class entity.Class
+	if err := common.DBConn.First(&class, "id = ?", student.ClassID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy lớp")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	var department entity.Department
+	if err := common.DBConn.First(&department, "id = ?", class.DepartmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy khoa")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	result := meStudentClass{
+		Class:      class,
+		Department: department,
+	}
+
+	if class.HostInstructorID != "" {
+		var instructor entity.Instructor
+		if err := common.DBConn.Select("first_name", "last_name").First(&instructor, "id = ?", class.HostInstructorID).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+			}
+		}
+		result.HostInstructorName = instructor.FirstName + " " + instructor.LastName
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", result))
+}
+
+// [GET] /api/me/student/academic-summary
+func MeStudentAcademicSummaryGet(c *fiber.Ctx) error {
+	student, err := currentStudent(c)
+	if err != nil {
+		return err
+	}
+
+	var grades []entity.Grade
+	if err := common.DBConn.Scopes(scopePublishedGrades).Find(&grades, "student_id = ?", student.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	summary, _, err := studentAcademicRecord(grades)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", summary))
+}
+
+// [GET] /api/me/student/attempts
+func MeStudentAttemptGetAll(c *fiber.Ctx) error {
+	student, err := currentStudent(c)
+	if err != nil {
+		return err
+	}
+
+	var grades []entity.Grade
+	if err := common.DBConn.Scopes(scopePublishedGrades).Find(&grades, "student_id = ?", student.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	_, history, err := studentAcademicRecord(grades)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", history))
+}
@@ -24,7 +24,11 @@ func generateStudentId(departmentID uint, acdYear int) string {
 func StudentGetAll(c *fiber.Ctx) error {
 	var students []entity.Student
 
-	if err := common.DBConn.Preload("Grades").Preload("Registrations").Find(&students).Error; err != nil {
+	if err := common.DBConn.Preload("Grades").Preload("Registrations").Scopes(scopeDepartment(c, "department_id")).Find(&students).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := fillStudentGradeResults(students); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -46,7 +50,20 @@ func StudentGetById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
-	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", student))
+	if err := checkDepartmentScope(c, student.DepartmentID); err != nil {
+		return err
+	}
+
+	summary, history, err := studentAcademicRecord(student.Grades)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", studentDetail{
+		Student:         student,
+		AcademicSummary: summary,
+		AttemptHistory:  history,
+	}))
 }
 
 // [POST] /api/students
@@ -57,6 +74,10 @@ func StudentCreate(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
+	if err := checkDepartmentScope(c, bodyData.DepartmentID); err != nil {
+		return err
+	}
+
 	today := time.Now()
 	if bodyData.BirthDay.After(today) {
 		return fiber.NewError(fiber.StatusBadRequest, "Ngày sinh không hợp lệ")
@@ -148,6 +169,10 @@ func StudentUpdateById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, student.DepartmentID); err != nil {
+		return err
+	}
+
 	today := time.Now()
 	if bodyData.BirthDay.After(today) {
 		return fiber.NewError(fiber.StatusBadRequest, "Ngày sinh không hợp lệ")
@@ -220,6 +245,10 @@ func StudentDeleteById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, student.DepartmentID); err != nil {
+		return err
+	}
+
 	if err := common.DBConn.Delete(&student).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa sinh viên")
 	}
@@ -235,7 +264,7 @@ func StudentDeleteByListId(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	if err := common.DBConn.Where("id IN ?", bodyData.ListId).Delete(&entity.Student{}).Error; err != nil {
+	if err := common.DBConn.Where("id IN ?", bodyData.ListId).Scopes(scopeDepartment(c, "department_id")).Delete(&entity.Student{}).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy sinh viên")
 		}
@@ -247,7 +276,7 @@ func StudentDeleteByListId(c *fiber.Ctx) error {
 
 // [DELETE] /api/students
 func StudentDeleteAll(c *fiber.Ctx) error {
-	if err := common.DBConn.Where("1 = 1").Delete(&entity.Student{}).Error; err != nil {
+	if err := common.DBConn.Where("1 = 1").Scopes(scopeDepartment(c, "department_id")).Delete(&entity.Student{}).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa tất cả sinh viên")
 	}
 
@@ -257,11 +286,20 @@ func StudentDeleteAll(c *fiber.Ctx) error {
 // [GET] /api/students/departments/:departmentID
 func GetStudentsByDepartmentID(c *fiber.Ctx) error {
 	departmentID := c.Params("departmentID")
+
+	if err := checkDepartmentScopeById(c, departmentID); err != nil {
+		return err
+	}
+
 	var students []entity.Student
 
 	if err := common.DBConn.Preload("Grades").Find(&students, "department_id = ?", departmentID).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := fillStudentGradeResults(students); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", students))
 }
@@ -18,12 +18,130 @@ func generateSubjectID(departmentID uint) string {
 	return idPrefix + departmentCode + common.GenerateRandNum(maxLength-len(idPrefix)-len(departmentCode))
 }
 
+// requestSubjectComponents builds the components of a subject from the request, or from the process, midterm and
+// final percentages when the request gives none.
+func requestSubjectComponents(components []req.SubjectComponent, processPercentage int8, midtermPercentage int8, finalPercentage int8) ([]entity.AssessmentComponent, error) {
+	var result []entity.AssessmentComponent
+	if len(components) == 0 {
+		result = common.LegacyComponents("", processPercentage, midtermPercentage, finalPercentage)
+	} else {
+		result = make([]entity.AssessmentComponent, 0, len(components))
+		for _, component := range components {
+			result = append(result, entity.AssessmentComponent{
+				Code:       component.Code,
+				Name:       component.Name,
+				Percentage: component.Percentage,
+			})
+		}
+	}
+
+	if message := common.CheckComponentPercentages(result); message != "" {
+		return nil, fiber.NewError(fiber.StatusBadRequest, message)
+	}
+
+	return result, nil
+}
+
+// checkRemovedComponents refuses to drop a component the subject already has scores in.
+// checkChangedComponents refuses new components or weights once grades of the subject were submitted or published,
+// the grades entered for the old components would no longer add up. Draft terms follow the change.
+func checkChangedComponents(subject entity.Subject, components []entity.AssessmentComponent) error {
+	current := common.SubjectComponents(subject)
+	changed := len(current) != len(components)
+	if !changed {
+		percentages := make(map[string]int8, len(current))
+		for _, component := range current {
+			percentages[component.Code] = component.Percentage
+		}
+		for _, component := range components {
+			if percentage, ok := percentages[component.Code]; !ok || percentage != component.Percentage {
+				changed = true
+				break
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	settledTerms := common.DBConn.Model(&entity.GradeSheet{}).Select("term_id").
+		Where("subject_id = ? AND status <> ?", subject.ID, entity.GradeSheetStatusDraft)
+
+	var count int64
+	if err := common.DBConn.Model(&entity.Grade{}).
+		Where("subject_id = ? AND (term_id IS NULL OR term_id IN (?))", subject.ID, settledTerms).
+		Count(&count).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if count > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Không thể thay đổi thành phần điểm hoặc trọng số khi môn học đã có bảng điểm được nộp")
+	}
+
+	return nil
+}
+
+func checkRemovedComponents(subject entity.Subject, components []entity.AssessmentComponent) error {
+	codes := make(map[string]bool, len(components))
+	for _, component := range components {
+		codes[component.Code] = true
+	}
+
+	removed := make([]string, 0)
+	for _, component := range common.SubjectComponents(subject) {
+		if !codes[component.Code] {
+			removed = append(removed, component.Code)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	gradesOfSubject := common.DBConn.Model(&entity.Grade{}).Select("id").Where("subject_id = ?", subject.ID)
+
+	var count int64
+	if err := common.DBConn.Model(&entity.GradeComponentScore{}).
+		Where("code IN ? AND grade_id IN (?)", removed, gradesOfSubject).
+		Count(&count).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	// Grades recorded before components were configurable keep their scores in the process, midterm and final columns
+	if count == 0 && len(subject.Components) == 0 {
+		if err := common.DBConn.Model(&entity.Grade{}).
+			Where("subject_id = ? AND id NOT IN (?)", subject.ID, common.DBConn.Model(&entity.GradeComponentScore{}).Select("grade_id")).
+			Count(&count).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+	}
+
+	if count > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Không thể xóa thành phần điểm đã có điểm")
+	}
+
+	return nil
+}
+
+func saveSubject(tx *gorm.DB, subject *entity.Subject) error {
+	if err := tx.Omit("Components").Save(subject).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&entity.AssessmentComponent{}, "subject_id = ?", subject.ID).Error; err != nil {
+		return err
+	}
+	return tx.Create(&subject.Components).Error
+}
+
 // [GET] /api/subjects
 func SubjectGetAll(c *fiber.Ctx) error {
 
 	var subjects []entity.Subject
 
-	if err := common.DBConn.Preload("Grades").Preload("StudentRegistrations").Preload("InstructorAssignments").Find(&subjects).Error; err != nil {
+	if err := common.DBConn.Preload("Grades").Preload("StudentRegistrations").Preload("InstructorAssignments").Scopes(scopeDepartment(c, "department_id")).Find(&subjects).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := fillSubjectGradeResults(subjects); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
@@ -41,20 +159,26 @@ func SubjectCreate(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	if totalPercentage := bodyData.ProcessPercentage + bodyData.MidtermPercentage + bodyData.FinalPercentage; totalPercentage != 100 {
-		return fiber.NewError(fiber.StatusBadRequest, "Tổng % phải bằng 100")
+	if err := checkDepartmentScope(c, bodyData.DepartmentID); err != nil {
+		return err
+	}
+
+	components, err := requestSubjectComponents(bodyData.Components, bodyData.ProcessPercentage, bodyData.MidtermPercentage, bodyData.FinalPercentage)
+	if err != nil {
+		return err
 	}
+
 	newSubject := entity.Subject{
-		ID:                generateSubjectID(bodyData.DepartmentID),
-		Name:              bodyData.Name,
-		Credits:           bodyData.Credits,
-		ProcessPercentage: bodyData.ProcessPercentage,
-		MidtermPercentage: bodyData.MidtermPercentage,
-		FinalPercentage:   bodyData.FinalPercentage,
-		DepartmentID:      bodyData.DepartmentID,
+		ID:           generateSubjectID(bodyData.DepartmentID),
+		Name:         bodyData.Name,
+		Credits:      bodyData.Credits,
+		DepartmentID: bodyData.DepartmentID,
 	}
+	common.SetSubjectComponents(&newSubject, components)
 
-	if err := common.DBConn.Create(&newSubject).Error; err != nil {
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		return saveSubject(tx, &newSubject)
+	}); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo môn học")
 	}
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", newSubject))
@@ -65,12 +189,21 @@ func SubjectGetById(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var subject entity.Subject
 
-	if err := common.DBConn.Preload("Grades").Preload("StudentRegistrations").Preload("InstructorAssignments").First(&subject, "id = ?", id).Error; err != nil {
+	if err := common.DBConn.Preload("Components", preloadAssessmentComponents).Preload("Grades").Preload("StudentRegistrations").Preload("InstructorAssignments").First(&subject, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy môn học")
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
+
+	if err := checkDepartmentScope(c, subject.DepartmentID); err != nil {
+		return err
+	}
+
+	if err := fillGradeResults(subject.Grades); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", subject))
 }
 
@@ -85,24 +218,37 @@ func SubjectUpdateById(c *fiber.Ctx) error {
 	var subject entity.Subject
 
 	subjectId := c.Params("id")
-	if err := common.DBConn.First(&subject, "id = ?", subjectId).Error; err != nil {
+	if err := common.DBConn.Preload("Components", preloadAssessmentComponents).First(&subject, "id = ?", subjectId).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy môn học")
 		}
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
-	if totalPercentage := bodyData.ProcessPercentage + bodyData.MidtermPercentage + bodyData.FinalPercentage; totalPercentage != 100 {
-		return fiber.NewError(fiber.StatusBadRequest, "Tổng % phải bằng 100")
+	if err := checkDepartmentScope(c, subject.DepartmentID); err != nil {
+		return err
+	}
+
+	components, err := requestSubjectComponents(bodyData.Components, bodyData.ProcessPercentage, bodyData.MidtermPercentage, bodyData.FinalPercentage)
+	if err != nil {
+		return err
+	}
+
+	if err := checkChangedComponents(subject, components); err != nil {
+		return err
+	}
+
+	if err := checkRemovedComponents(subject, components); err != nil {
+		return err
 	}
 
 	subject.Name = bodyData.Name
 	subject.Credits = bodyData.Credits
-	subject.ProcessPercentage = bodyData.ProcessPercentage
-	subject.MidtermPercentage = bodyData.MidtermPercentage
-	subject.FinalPercentage = bodyData.FinalPercentage
+	common.SetSubjectComponents(&subject, components)
 
-	if err := common.DBConn.Save(&subject).Error; err != nil {
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		return saveSubject(tx, &subject)
+	}); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật môn học")
 	}
 
@@ -121,6 +267,10 @@ func SubjectDeleteById(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := checkDepartmentScope(c, subject.DepartmentID); err != nil {
+		return err
+	}
+
 	if err := common.DBConn.Delete(&subject).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa môn học")
 	}
@@ -136,7 +286,7 @@ func SubjectDeleteByListId(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	if err := common.DBConn.Where("id IN ?", bodyData.ListId).Delete(&entity.Subject{}).Error; err != nil {
+	if err := common.DBConn.Where("id IN ?", bodyData.ListId).Scopes(scopeDepartment(c, "department_id")).Delete(&entity.Subject{}).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy môn học")
 		}
@@ -148,7 +298,7 @@ func SubjectDeleteByListId(c *fiber.Ctx) error {
 
 // [DELETE] /api/subjects
 func SubjectDeleteAll(c *fiber.Ctx) error {
-	if err := common.DBConn.Where("1 = 1").Delete(&entity.Subject{}).Error; err != nil {
+	if err := common.DBConn.Where("1 = 1").Scopes(scopeDepartment(c, "department_id")).Delete(&entity.Subject{}).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa tất cả môn học")
 	}
 
@@ -158,11 +308,20 @@ func SubjectDeleteAll(c *fiber.Ctx) error {
 // [GET] /api/subjects/department/:departmentID
 func GetSubjectsByDepartmentID(c *fiber.Ctx) error {
 	departmentID := c.Params("departmentID")
+
+	if err := checkDepartmentScopeById(c, departmentID); err != nil {
+		return err
+	}
+
 	var subjects []entity.Subject
 
 	if err := common.DBConn.Preload("Grades").Preload("Assignments").Find(&subjects, "department_id = ?", departmentID).Error; err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
 	}
 
+	if err := fillSubjectGradeResults(subjects); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
 	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", subjects))
 }
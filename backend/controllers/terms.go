@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"time"
+)
+
+// currentTerm returns the active term running today, or the latest active term between two terms.
+func currentTerm() (*entity.AcademicTerm, error) {
+	var term entity.AcademicTerm
+	now := time.Now()
+
+	err := common.DBConn.Where("status = ?", entity.TermStatusActive).
+		Order(gorm.Expr("(start_date <= ? AND end_date >= ?) desc", now, now)).
+		Order("start_date desc").
+		First(&term).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Chưa có học kỳ nào đang diễn ra, vui lòng chọn học kỳ")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return &term, nil
+}
+
+// openTerm loads the term new records are attached to, the current term when termId is 0.
+// Closed terms no longer accept registrations, assignments or grades.
+func openTerm(termId uint) (*entity.AcademicTerm, error) {
+	if termId == 0 {
+		return currentTerm()
+	}
+
+	var term entity.AcademicTerm
+	if err := common.DBConn.First(&term, "id = ?", termId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy học kỳ")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if term.Status == entity.TermStatusClosed {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Học kỳ đã kết thúc")
+	}
+
+	return &term, nil
+}
+
+// updatedTerm returns the term an update moves a record to, storedTermId itself while the term stays. A term_id left
+// out keeps the stored term, and only a different term has to be open.
+func updatedTerm(storedTermId *uint, termId uint) (*uint, error) {
+	if termId == 0 || (storedTermId != nil && *storedTermId == termId) {
+		return storedTermId, nil
+	}
+
+	term, err := openTerm(termId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &term.ID, nil
+}
+
+// scopeTermId limits a query to the records of the term, nil to the ones recorded before terms existed.
+func scopeTermId(termId *uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if termId == nil {
+			return db.Where("term_id IS NULL")
+		}
+		return db.Where("term_id = ?", *termId)
+	}
+}
+
+// scopeTerm limits a query to the term given by the term_id query parameter, if any.
+func scopeTerm(c *fiber.Ctx) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		termId := c.QueryInt("term_id")
+		if termId <= 0 {
+			return db
+		}
+		return db.Where("term_id = ?", termId)
+	}
+}
+
+func checkTermDates(startDate time.Time, endDate time.Time) error {
+	if !endDate.After(startDate) {
+		return fiber.NewError(fiber.StatusBadRequest, "Ngày kết thúc phải sau ngày bắt đầu")
+	}
+	return nil
+}
+
+// [GET] /api/terms
+func TermGetAll(c *fiber.Ctx) error {
+	var terms []entity.AcademicTerm
+
+	query := common.DBConn.Order("year desc").Order("semester desc")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&terms).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", terms))
+}
+
+// [GET] /api/terms/current
+func TermGetCurrent(c *fiber.Ctx) error {
+	term, err := currentTerm()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", term))
+}
+
+// [GET] /api/terms/:id
+func TermGetById(c *fiber.Ctx) error {
+	termId := c.Params("id")
+	var term entity.AcademicTerm
+
+	if err := common.DBConn.First(&term, "id = ?", termId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy học kỳ")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", term))
+}
+
+// [POST] /api/terms
+func TermCreate(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.TermCreate](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := checkTermDates(bodyData.StartDate, bodyData.EndDate); err != nil {
+		return err
+	}
+
+	var term entity.AcademicTerm
+	if err := common.DBConn.Select("id").First(&term, "year = ? AND semester = ?", bodyData.Year, bodyData.Semester).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+	}
+
+	if term.ID != 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Học kỳ đã tồn tại")
+	}
+
+	newTerm := entity.AcademicTerm{
+		Year:      bodyData.Year,
+		Semester:  bodyData.Semester,
+		StartDate: bodyData.StartDate,
+		EndDate:   bodyData.EndDate,
+		Status:    bodyData.Status,
+	}
+
+	if err := common.DBConn.Create(&newTerm).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo học kỳ")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", newTerm))
+}
+
+// [PUT] /api/terms/:id
+func TermUpdateById(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.TermUpdateById](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := checkTermDates(bodyData.StartDate, bodyData.EndDate); err != nil {
+		return err
+	}
+
+	termId := c.Params("id")
+	var term entity.AcademicTerm
+
+	if err := common.DBConn.First(&term, "id = ?", termId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy học kỳ")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	term.StartDate = bodyData.StartDate
+	term.EndDate = bodyData.EndDate
+	term.Status = bodyData.Status
+
+	if err := common.DBConn.Save(&term).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật học kỳ")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", term))
+}
+
+// [DELETE] /api/terms/:id
+func TermDeleteById(c *fiber.Ctx) error {
+	termId := c.Params("id")
+	var term entity.AcademicTerm
+
+	if err := common.DBConn.First(&term, "id = ?", termId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy học kỳ")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	// Terms that already hold records are closed instead of deleted
+	for _, model := range []any{&entity.StudentRegistration{}, &entity.InstructorAssignment{}, &entity.Grade{}} {
+		var count int64
+		if err := common.DBConn.Model(model).Where("term_id = ?", term.ID).Count(&count).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+		if count > 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Học kỳ đã có dữ liệu, không thể xóa")
+		}
+	}
+
+	if err := common.DBConn.Delete(&term).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi xóa học kỳ")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", term))
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+)
+
+// Students are loaded with their grades a batch at a time, each of them gets a sheet of the file
+const transcriptExportBatchSize = 50
+
+var transcriptExportHeaders = []interface{}{"Học kỳ", "Mã môn học", "Môn học", "Số tín chỉ", "Lần học", "Điểm thành phần", "Điểm tổng kết", "Điểm hệ 4", "Điểm chữ", "Kết quả", "Tính vào GPA"}
+
+func transcriptExportQuery(params entity.ExportJobParams) *gorm.DB {
+	query := common.DBConn.Model(&entity.Student{})
+
+	if params.StudentID != "" {
+		query = query.Where("id = ?", params.StudentID)
+	}
+	if params.ClassID != "" {
+		query = query.Where("class_id = ?", params.ClassID)
+	}
+	if params.Scoped {
+		if len(params.DepartmentIds) == 0 {
+			return query.Where("1 = 0")
+		}
+		query = query.Where("department_id IN ?", params.DepartmentIds)
+	}
+
+	return query
+}
+
+// buildTranscriptExport writes the transcript of every student of the params to its own sheet of a new file.
+// The caller closes the file.
+func buildTranscriptExport(params entity.ExportJobParams, progress exportProgress) (*excelize.File, error) {
+	f := excelize.NewFile()
+	stop := func(err error) (*excelize.File, error) {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+		return nil, err
+	}
+	fail := func(err error) (*excelize.File, error) {
+		return stop(fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Lỗi khi tạo file excel: %v", err)))
+	}
+
+	var total int64
+	if err := transcriptExportQuery(params).Count(&total).Error; err != nil {
+		return stop(fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu"))
+	}
+	if total == 0 {
+		return stop(fiber.NewError(fiber.StatusBadRequest, "Không có sinh viên nào để xuất bảng điểm"))
+	}
+	if progress != nil {
+		if err := progress(0, int(total)); err != nil {
+			return stop(err)
+		}
+	}
+
+	done := 0
+	lastId := ""
+	for {
+		var students []entity.Student
+		// A transcript only has the grades students may see, like the student portal
+		if err := transcriptExportQuery(params).Preload("Grades", scopePublishedGrades).Preload("Grades.Components").Where("id > ?", lastId).Order("id").
+			Limit(transcriptExportBatchSize).Find(&students).Error; err != nil {
+			return stop(fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu"))
+		}
+		if len(students) == 0 {
+			break
+		}
+		lastId = students[len(students)-1].ID
+
+		subjects, termCodes, err := transcriptRefs(students)
+		if err != nil {
+			return fail(err)
+		}
+
+		for _, student := range students {
+			if done == 0 {
+				err = f.SetSheetName("Sheet1", student.ID)
+			} else {
+				_, err = f.NewSheet(student.ID)
+			}
+			if err != nil {
+				return fail(err)
+			}
+
+			if err := writeTranscript(f, student, subjects, termCodes); err != nil {
+				return fail(err)
+			}
+			done++
+		}
+
+		if progress != nil {
+			if err := progress(done, int(total)); err != nil {
+				return stop(err)
+			}
+		}
+	}
+
+	return f, nil
+}
+
+// transcriptRefs loads the subjects, with their components, and the codes of the terms of the grades of the students.
+func transcriptRefs(students []entity.Student) (map[string]entity.Subject, map[uint]string, error) {
+	subjectsId := make([]string, 0)
+	termsId := make([]uint, 0)
+	for _, student := range students {
+		for _, grade := range student.Grades {
+			subjectsId = append(subjectsId, grade.SubjectID)
+			if grade.TermID != nil {
+				termsId = append(termsId, *grade.TermID)
+			}
+		}
+	}
+
+	subjectsById := make(map[string]entity.Subject)
+	if len(subjectsId) > 0 {
+		var subjects []entity.Subject
+		if err := common.DBConn.Preload("Components", preloadAssessmentComponents).Find(&subjects, "id IN ?", subjectsId).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, subject := range subjects {
+			subjectsById[subject.ID] = subject
+		}
+	}
+
+	termCodes := make(map[uint]string)
+	if len(termsId) > 0 {
+		var terms []entity.AcademicTerm
+		if err := common.DBConn.Find(&terms, "id IN ?", termsId).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, term := range terms {
+			termCodes[term.ID] = term.Code()
+		}
+	}
+
+	return subjectsById, termCodes, nil
+}
+
+func writeTranscript(f *excelize.File, student entity.Student, subjects map[string]entity.Subject, termCodes map[uint]string) error {
+	summary, history, err := studentAcademicRecord(student.Grades)
+	if err != nil {
+		return err
+	}
+
+	sw, err := f.NewStreamWriter(student.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := sw.SetColWidth(1, len(transcriptExportHeaders), 18); err != nil {
+		return err
+	}
+
+	rows := [][]interface{}{
+		{"Mã sinh viên", student.ID},
+		{"Họ tên", student.FirstName + " " + student.LastName},
+		{"Lớp", student.ClassID},
+		{},
+		transcriptExportHeaders,
+	}
+
+	for _, subject := range history {
+		for _, attempt := range subject.Attempts {
+			term := common.GradeTerm(attempt.CreatedAt)
+			if attempt.TermID != nil {
+				term = termCodes[*attempt.TermID]
+			}
+
+			counted := "Không"
+			if attempt.Counted {
+				counted = "Có"
+			}
+
+			rows = append(rows, []interface{}{
+				term,
+				subject.SubjectID,
+				subject.SubjectName,
+				int(subjects[subject.SubjectID].Credits),
+				attempt.Attempt,
+				common.ComponentsText(attempt.Grade, subjects[subject.SubjectID]),
+				fmt.Sprintf("%.1f", attempt.TotalScore),
+				fmt.Sprintf("%.1f", attempt.GPAScore),
+				attempt.LetterGrade,
+				attempt.ResultText,
+				counted,
+			})
+		}
+	}
+
+	warning := "Không"
+	if summary.AcademicWarning {
+		warning = "Có"
+	}
+
+	rows = append(rows,
+		[]interface{}{},
+		[]interface{}{"Tín chỉ đã học", summary.AttemptedCredits},
+		[]interface{}{"Tín chỉ tích lũy", summary.EarnedCredits},
+		[]interface{}{"GPA hệ 10", fmt.Sprintf("%.2f", summary.CumulativeGPA10)},
+		[]interface{}{"GPA hệ 4", fmt.Sprintf("%.2f", summary.CumulativeGPA4)},
+		[]interface{}{"Xếp loại", summary.Classification},
+		[]interface{}{"Cảnh báo học vụ", warning},
+	)
+
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err := sw.SetRow(cell, row); err != nil {
+			return err
+		}
+	}
+
+	return sw.Flush()
+}
@@ -0,0 +1,429 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"os"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	challengeTokenType = "2fa_challenge"
+	recoveryCodeCount  = 10
+)
+
+type twoFactorChallenge struct {
+	ChallengeToken string `json:"challenge_token"`
+	TOTPEnabled    bool   `json:"totp_enabled"`
+}
+
+type twoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type twoFactorResult struct {
+	User          entity.User `json:"user"`
+	RecoveryCodes []string    `json:"recovery_codes,omitempty"`
+}
+
+// twoFactorRequired tells whether the user must sign in with a second factor, set on the user itself or for its
+// role through TOTP_REQUIRED_ROLES.
+func twoFactorRequired(user entity.User) bool {
+	if user.TOTPRequired {
+		return true
+	}
+	for _, role := range strings.Split(common.GetEnvString("TOTP_REQUIRED_ROLES", entity.RoleAdmin+","+entity.RoleDepartmentManager), ",") {
+		if strings.TrimSpace(role) == user.Role {
+			return true
+		}
+	}
+	return false
+}
+
+// createChallengeJWT issues the short-lived token that AuthLogin hands out instead of a session while the second factor is pending.
+func createChallengeJWT(userId uint) (string, error) {
+	claims := jwt.MapClaims{
+		"uid": strconv.Itoa(int(userId)),
+		"typ": challengeTokenType,
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
+	tokenSignedString, tokenSignedErr := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+
+	if tokenSignedErr != nil {
+		return "", errors.New("Có lỗi trong khi tạo token")
+	}
+	return tokenSignedString, nil
+}
+
+func challengeUser(challengeToken string) (*entity.User, error) {
+	invalidErr := fiber.NewError(fiber.StatusUnauthorized, "Phiên xác thực hai bước không hợp lệ hoặc đã hết hạn")
+
+	token, err := jwt.Parse(challengeToken, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS512.Alg()}))
+
+	if err != nil || !token.Valid {
+		return nil, invalidErr
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if typ, _ := claims["typ"].(string); typ != challengeTokenType {
+		return nil, invalidErr
+	}
+
+	uid, _ := claims["uid"].(string)
+
+	var user entity.User
+	if err := common.DBConn.First(&user, "id = ?", uid).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidErr
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	return &user, nil
+}
+
+// startTOTPSetup stores a new, not yet enabled secret for the user.
+func startTOTPSetup(user *entity.User) (*twoFactorSetup, error) {
+	if user.TOTPEnabled {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Tài khoản đã bật xác thực hai bước")
+	}
+
+	secret, err := common.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo mã bí mật")
+	}
+
+	if err := common.DBConn.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "QLDiemSV"
+	}
+
+	return &twoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: common.TOTPProvisioningURI(issuer, user.UserName, secret),
+	}, nil
+}
+
+// verifyTOTP accepts each time step only once, so an intercepted code cannot be replayed.
+func verifyTOTP(user *entity.User, code string) (bool, error) {
+	if user.TOTPSecret == "" {
+		return false, nil
+	}
+
+	step, ok := common.MatchTOTP(user.TOTPSecret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return false, nil
+	}
+
+	result := common.DBConn.Model(&entity.User{}).Where("id = ? and totp_last_step < ?", user.ID, step).Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	user.TOTPLastStep = step
+	return result.RowsAffected == 1, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func verifyRecoveryCode(user *entity.User, code string) (bool, error) {
+	result := common.DBConn.Model(&entity.RecoveryCode{}).
+		Where("user_id = ? and code_hash = ? and used_at IS NULL", user.ID, common.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+
+	if result.Error != nil {
+		return false, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// verifySecondFactor accepts either a TOTP code or one of the unused recovery codes.
+func verifySecondFactor(user *entity.User, code string) (bool, error) {
+	if ok, err := verifyTOTP(user, code); err != nil || ok {
+		return ok, err
+	}
+
+	return verifyRecoveryCode(user, code)
+}
+
+// issueRecoveryCodes replaces the recovery codes of the user, the plain codes are only returned this once.
+func issueRecoveryCodes(userId uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]entity.RecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := common.GenerateRandToken(5)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo mã khôi phục")
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		records = append(records, entity.RecoveryCode{CodeHash: common.HashToken(code), UserID: userId})
+	}
+
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	}); err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tạo mã khôi phục")
+	}
+
+	return codes, nil
+}
+
+// enableTOTP turns on the pending secret once the user proved the authenticator works.
+func enableTOTP(user *entity.User, code string) ([]string, bool, error) {
+	if user.TOTPSecret == "" {
+		return nil, false, fiber.NewError(fiber.StatusBadRequest, "Chưa khởi tạo xác thực hai bước")
+	}
+
+	ok, err := verifyTOTP(user, code)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	if err := common.DBConn.Model(user).Update("totp_enabled", true).Error; err != nil {
+		return nil, false, fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi truy vấn cơ sở dữ liệu")
+	}
+
+	codes, err := issueRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return codes, true, nil
+}
+
+// [POST] /api/auth/2fa/enroll
+func AuthTwoFactorEnroll(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.AuthTwoFactorEnroll](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := challengeUser(bodyData.ChallengeToken)
+	if err != nil {
+		return err
+	}
+
+	setup, err := startTOTPSetup(user)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", setup))
+}
+
+// [POST] /api/auth/2fa/verify
+func AuthTwoFactorVerify(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.AuthTwoFactorVerify](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := challengeUser(bodyData.ChallengeToken)
+	if err != nil {
+		return err
+	}
+
+	if err := checkLoginThrottle(loginThrottleKeys(c, user.UserName)); err != nil {
+		return err
+	}
+
+	var recoveryCodes []string
+	var ok bool
+
+	// A required but not yet enrolled account finishes its enrollment here
+	if !user.TOTPEnabled {
+		recoveryCodes, ok, err = enableTOTP(user, bodyData.Code)
+	} else {
+		ok, err = verifySecondFactor(user, bodyData.Code)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		if err := recordLoginFailures(c, user.UserName); err != nil {
+			return err
+		}
+		return fiber.NewError(fiber.StatusBadRequest, "Mã xác thực không đúng")
+	}
+
+	if err := clearLoginFailures(c, user.UserName); err != nil {
+		return err
+	}
+
+	if err := issueSession(c, *user); err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Đăng nhập thành công", twoFactorResult{User: *user, RecoveryCodes: recoveryCodes}))
+}
+
+// [POST] /api/auth/2fa/setup
+func AuthTwoFactorSetup(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	setup, err := startTOTPSetup(user)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", setup))
+}
+
+// [POST] /api/auth/2fa/enable
+func AuthTwoFactorEnable(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.AuthTwoFactorCode](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if user.TOTPEnabled {
+		return fiber.NewError(fiber.StatusBadRequest, "Tài khoản đã bật xác thực hai bước")
+	}
+
+	recoveryCodes, ok, err := enableTOTP(user, bodyData.Code)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "Mã xác thực không đúng")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", recoveryCodes))
+}
+
+// [POST] /api/auth/2fa/disable
+func AuthTwoFactorDisable(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.AuthTwoFactorCode](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if twoFactorRequired(*user) {
+		return fiber.NewError(fiber.StatusForbidden, "Tài khoản bắt buộc phải bật xác thực hai bước")
+	}
+
+	if !user.TOTPEnabled {
+		return fiber.NewError(fiber.StatusBadRequest, "Tài khoản chưa bật xác thực hai bước")
+	}
+
+	ok, err := verifySecondFactor(user, bodyData.Code)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "Mã xác thực không đúng")
+	}
+
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{"totp_secret": "", "totp_enabled": false, "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&entity.RecoveryCode{}).Error
+	}); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Có lỗi trong khi tắt xác thực hai bước")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", nil))
+}
+
+// [POST] /api/auth/2fa/recovery-codes
+func AuthTwoFactorRecoveryCodes(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.AuthTwoFactorCode](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled {
+		return fiber.NewError(fiber.StatusBadRequest, "Tài khoản chưa bật xác thực hai bước")
+	}
+
+	ok, err := verifyTOTP(user, bodyData.Code)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "Mã xác thực không đúng")
+	}
+
+	recoveryCodes, err := issueRecoveryCodes(user.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", recoveryCodes))
+}
+
+// [PUT] /api/users/:id/2fa
+func UserUpdateTwoFactorById(c *fiber.Ctx) error {
+	userId := c.Params("id")
+	bodyData, err := common.Validator[req.UserUpdateTwoFactorById](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var user entity.User
+	if err := common.DBConn.First(&user, "id = ?", userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy user")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := common.DBConn.Model(&user).Update("totp_required", bodyData.Required).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật user")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", user))
+}
@@ -1,9 +1,380 @@
 package controllers
 
 import (
+	"errors"
 	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"qldiemsv/models/req"
+	"strconv"
+	"time"
 )
 
+func currentUser(c *fiber.Ctx) (*entity.User, error) {
+	currentUserId, currentUserIdIsOk := c.Locals("currentUserId").(string)
+
+	if !currentUserIdIsOk {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var user entity.User
+	if err := common.DBConn.First(&user, "id = ?", currentUserId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusUnauthorized, "Không tìm thấy user")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return &user, nil
+}
+
+// checkUserUnique also looks at disabled accounts, the unique indexes cover them too.
+func checkUserUnique(userName string, email string) error {
+	var existUser entity.User
+	if err := common.DBConn.Unscoped().Select("id").First(&existUser, "user_name = ?", userName).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+	}
+
+	if existUser.ID != 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Username đã tồn tại")
+	}
+
+	if email == "" {
+		return nil
+	}
+
+	if err := common.DBConn.Unscoped().Select("id").First(&existUser, "email = ?", email).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+	}
+
+	if existUser.ID != 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "Email đã tồn tại")
+	}
+
+	return nil
+}
+
+// checkNotCurrentUser stops admins from disabling or demoting their own account.
+func checkNotCurrentUser(c *fiber.Ctx, user entity.User) error {
+	currentUserId, _ := c.Locals("currentUserId").(string)
+
+	if currentUserId == strconv.Itoa(int(user.ID)) {
+		return fiber.NewError(fiber.StatusBadRequest, "Không thể thực hiện thao tác này trên tài khoản của chính mình")
+	}
+
+	return nil
+}
+
+// [GET] /api/users
+func UserGetAll(c *fiber.Ctx) error {
+	var users []entity.User
+
+	query := common.DBConn.Preload("Departments")
+	switch c.Query("status") {
+	case "disabled":
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	case "all":
+		query = query.Unscoped()
+	}
+
+	if err := query.Order("id").Find(&users).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", users))
+}
+
+// [GET] /api/users/:id
+func UserGetById(c *fiber.Ctx) error {
+	userId := c.Params("id")
+	var user entity.User
+
+	if err := common.DBConn.Unscoped().Preload("Departments").Preload("Instructor").Preload("Student").First(&user, "id = ?", userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy user")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", user))
+}
+
+// [POST] /api/users
+func UserCreate(c *fiber.Ctx) error {
+	bodyData, err := common.Validator[req.UserCreate](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if !common.IsValidRole(bodyData.Role) {
+		return fiber.NewError(fiber.StatusBadRequest, "Role không hợp lệ")
+	}
+
+	if err := common.ValidatePassword(bodyData.Password); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := checkUserUnique(bodyData.UserName, bodyData.Email); err != nil {
+		return err
+	}
+
+	hashPassword, hashPasswordErr := bcrypt.GenerateFromPassword([]byte(bodyData.Password), 11)
+
+	if hashPasswordErr != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo user")
+	}
+
+	newUser := entity.User{
+		FirstName: bodyData.FirstName,
+		LastName:  bodyData.LastName,
+		UserName:  bodyData.UserName,
+		Password:  string(hashPassword),
+		Role:      bodyData.Role,
+	}
+
+	if bodyData.Email != "" {
+		newUser.Email = &bodyData.Email
+	}
+
+	if err := common.DBConn.Create(&newUser).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi tạo user")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(common.NewResponse(fiber.StatusCreated, "Success", newUser))
+}
+
+// [PUT] /api/users/:id/role
+func UserUpdateRoleById(c *fiber.Ctx) error {
+	userId := c.Params("id")
+	bodyData, err := common.Validator[req.UserUpdateRoleById](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if !common.IsValidRole(bodyData.Role) {
+		return fiber.NewError(fiber.StatusBadRequest, "Role không hợp lệ")
+	}
+
+	var user entity.User
+	if err := common.DBConn.First(&user, "id = ?", userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy user")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := checkNotCurrentUser(c, user); err != nil {
+		return err
+	}
+
+	// The role is carried in the access token, so the user has to log in again to pick up the new one
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("role", bodyData.Role).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.UserSession{}).Where("user_id = ? and revoked_at IS NULL", user.ID).Update("revoked_at", time.Now()).Error
+	}); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật user")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", user))
+}
+
+// [DELETE] /api/users/:id
+func UserDisableById(c *fiber.Ctx) error {
+	userId := c.Params("id")
+	var user entity.User
+
+	if err := common.DBConn.First(&user, "id = ?", userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy user")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := checkNotCurrentUser(c, user); err != nil {
+		return err
+	}
+
+	// A disabled user is soft deleted, which keeps them out of login, and every open session is revoked
+	if err := common.DBConn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.UserSession{}).Where("user_id = ? and revoked_at IS NULL", user.ID).Update("revoked_at", time.Now()).Error
+	}); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi vô hiệu hoá user")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", nil))
+}
+
+// [POST] /api/users/:id/restore
+func UserRestoreById(c *fiber.Ctx) error {
+	userId := c.Params("id")
+	var user entity.User
+
+	if err := common.DBConn.Unscoped().First(&user, "id = ? and deleted_at IS NOT NULL", userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy user đã bị vô hiệu hoá")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if err := common.DBConn.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi khôi phục user")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", user))
+}
+
+// [GET] /api/users/me
 func UserMe(c *fiber.Ctx) error {
-	return c.SendString("UserMe")
+	user, err := currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if err := common.DBConn.Preload("Departments").Preload("Instructor").Preload("Student").First(user, "id = ?", user.ID).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", user))
+}
+
+// [PUT] /api/users/:id/departments
+func UserUpdateDepartmentsById(c *fiber.Ctx) error {
+	userId := c.Params("id")
+	bodyData, err := common.Validator[req.UserUpdateDepartmentsById](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var user entity.User
+	if err := common.DBConn.First(&user, "id = ?", userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy user")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	departments, err := findDepartments(bodyData.DepartmentIDs)
+	if err != nil {
+		return err
+	}
+
+	if err := common.DBConn.Model(&user).Association("Departments").Replace(departments); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật khoa của user")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", user))
+}
+
+// [PUT] /api/users/:id/instructor
+func UserUpdateInstructorById(c *fiber.Ctx) error {
+	userId := c.Params("id")
+	bodyData, err := common.Validator[req.UserUpdateInstructorById](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var user entity.User
+	if err := common.DBConn.First(&user, "id = ?", userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy user")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	// An empty instructor id unlinks the account
+	if bodyData.InstructorID == "" {
+		user.InstructorID = nil
+	} else {
+		var instructor entity.Instructor
+		if err := common.DBConn.Select("id").First(&instructor, "id = ?", bodyData.InstructorID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy giảng viên")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+
+		var existUser entity.User
+		if err := common.DBConn.Select("id").First(&existUser, "id <> ? and instructor_id = ?", user.ID, instructor.ID).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+			}
+		}
+
+		if existUser.ID != 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Giảng viên đã được liên kết với tài khoản khác")
+		}
+
+		user.InstructorID = &instructor.ID
+	}
+
+	if err := common.DBConn.Model(&user).Select("instructor_id").Updates(&user).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật user")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", user))
+}
+
+// [PUT] /api/users/:id/student
+func UserUpdateStudentById(c *fiber.Ctx) error {
+	userId := c.Params("id")
+	bodyData, err := common.Validator[req.UserUpdateStudentById](c)
+
+	if err != nil || bodyData == nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	var user entity.User
+	if err := common.DBConn.First(&user, "id = ?", userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy user")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	// An empty student id unlinks the account
+	if bodyData.StudentID == "" {
+		user.StudentID = nil
+	} else {
+		var student entity.Student
+		if err := common.DBConn.Select("id").First(&student, "id = ?", bodyData.StudentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusBadRequest, "Không tìm thấy sinh viên")
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+
+		var existUser entity.User
+		if err := common.DBConn.Select("id").First(&existUser, "id <> ? and student_id = ?", user.ID, student.ID).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+			}
+		}
+
+		if existUser.ID != 0 {
+			return fiber.NewError(fiber.StatusBadRequest, "Sinh viên đã được liên kết với tài khoản khác")
+		}
+
+		user.StudentID = &student.ID
+	}
+
+	if err := common.DBConn.Model(&user).Select("student_id").Updates(&user).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi cập nhật user")
+	}
+
+	return c.JSON(common.NewResponse(fiber.StatusOK, "Success", user))
 }
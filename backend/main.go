@@ -12,12 +12,15 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"os"
 	"qldiemsv/common"
+	"qldiemsv/controllers"
 	"qldiemsv/router"
 )
 
 func init() {
 	common.LoadEnvVar()
 	common.ConnectDB()
+	common.BootstrapAdmin()
+	common.SetupMailer()
 	folderPath := "static                    "
 
 	// Check if the folder exists
@@ -57,7 +60,7 @@ func main() {
 	app.Use(helmet.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:  os.Getenv("CLIENT_URL"),
-		ExposeHeaders: os.Getenv("JWT_HEADER"),
+		ExposeHeaders: os.Getenv("JWT_HEADER") + "," + os.Getenv("JWT_REFRESH_HEADER"),
 	}))
 	app.Use(etag.New())
 	app.Use(compress.New(compress.Config{
@@ -72,6 +75,7 @@ func main() {
 	//}))
 
 	router.SetupRouter(app)
+	controllers.StartExportJobs()
 	err := app.Listen(":" + os.Getenv("PORT"))
 	if err != nil {
 		panic(err)
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+	"time"
+)
+
+func apiKeyHeader() string {
+	return common.GetEnvString("API_KEY_HEADER", "X-API-Key")
+}
+
+// apiKeyAuth authenticates a request made with an API key. The key only gets the permissions it was issued
+// with, and is limited to its departments when it has any.
+func apiKeyAuth(c *fiber.Ctx, key string) error {
+	var apiKey entity.APIKey
+	if err := common.DBConn.First(&apiKey, "key_hash = ?", common.HashToken(key)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusUnauthorized, "API key không hợp lệ")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt)) {
+		return fiber.NewError(fiber.StatusUnauthorized, "API key không hợp lệ")
+	}
+
+	departmentIds := make([]uint, 0)
+	if err := common.DBConn.Table("api_key_departments").Where("api_key_id = ?", apiKey.ID).Pluck("department_id", &departmentIds).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	// Last use is recorded at most once a minute so busy integrations do not write on every request
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > time.Minute || apiKey.LastUsedIP != c.IP() {
+		if err := common.DBConn.Model(&apiKey).UpdateColumns(map[string]interface{}{"last_used_at": now, "last_used_ip": c.IP()}).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+	}
+
+	permissions := make([]string, 0, len(apiKey.Permissions))
+	for _, perm := range apiKey.Permissions {
+		if common.IsAPIKeyPermission(perm) {
+			permissions = append(permissions, perm)
+		}
+	}
+
+	c.Locals("currentApiKeyId", apiKey.ID)
+	c.Locals("currentPermissions", permissions)
+	if len(departmentIds) > 0 {
+		c.Locals("departmentScoped", true)
+		c.Locals("departmentScope", departmentIds)
+	}
+
+	return c.Next()
+}
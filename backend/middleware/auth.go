@@ -1,18 +1,30 @@
 package middleware
 
 import (
+	"errors"
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 	"os"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
 )
 
+// Protected accepts either a JWT or, for other systems, an API key in the API_KEY_HEADER header.
 func Protected() fiber.Handler {
-	return jwtware.New(jwtware.Config{
+	jwtHandler := jwtware.New(jwtware.Config{
 		SigningKey:     jwtware.SigningKey{Key: []byte(os.Getenv("JWT_SECRET"))},
 		SuccessHandler: jwtSuccess,
 		ErrorHandler:   jwtError,
 	})
+
+	return func(c *fiber.Ctx) error {
+		if key := c.Get(apiKeyHeader()); key != "" {
+			return apiKeyAuth(c, key)
+		}
+		return jwtHandler(c)
+	}
 }
 
 func jwtSuccess(c *fiber.Ctx) error {
@@ -22,7 +34,49 @@ func jwtSuccess(c *fiber.Ctx) error {
 	if !ok {
 		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired JWT")
 	}
+	sid, ok := claims["sid"].(string)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired JWT")
+	}
+	jti, ok := claims["jti"].(string)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired JWT")
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired JWT")
+	}
+
+	// Logging out revokes the session, which rejects every token issued for it
+	var session entity.UserSession
+	if err := common.DBConn.Select("id", "revoked_at").First(&session, "id = ?", sid).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired JWT")
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if session.RevokedAt != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired JWT")
+	}
+
+	// A single access token can also be revoked, by its ID
+	var revoked int64
+	if err := common.DBConn.Model(&entity.RevokedToken{}).Where("id = ?", jti).Count(&revoked).Error; err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+	}
+
+	if revoked > 0 {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid or expired JWT")
+	}
+
+	role, _ := claims["role"].(string)
 	c.Locals("currentUserId", uid)
+	c.Locals("currentSessionId", sid)
+	c.Locals("currentTokenId", jti)
+	c.Locals("currentTokenExpiresAt", exp.Time)
+	c.Locals("currentUserRole", role)
+	c.Locals("currentPermissions", common.RolePermissions(role))
 
 	return c.Next()
 }
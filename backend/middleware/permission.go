@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
+)
+
+// Permission only lets the request through when the current user holds every listed permission.
+func Permission(perms ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		granted, _ := c.Locals("currentPermissions").([]string)
+
+		for _, perm := range perms {
+			if !common.HasPermission(granted, perm) {
+				return fiber.NewError(fiber.StatusForbidden, "Bạn không có quyền thực hiện thao tác này")
+			}
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
+	"qldiemsv/models/entity"
+)
+
+// DepartmentScope loads the departments linked to the current user. Admins are never scoped, every other role
+// is, so a user linked to no department sees no department data. Instructors also reach the department of their
+// linked instructor and the departments of the subjects assigned to it. API keys get their scope from apiKeyAuth.
+func DepartmentScope() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, isApiKey := c.Locals("currentApiKeyId").(uint); isApiKey {
+			return c.Next()
+		}
+
+		role, _ := c.Locals("currentUserRole").(string)
+		if role == entity.RoleAdmin {
+			return c.Next()
+		}
+
+		currentUserId, _ := c.Locals("currentUserId").(string)
+
+		departmentIds := make([]uint, 0)
+		if err := common.DBConn.Table("user_departments").Where("user_id = ?", currentUserId).Pluck("department_id", &departmentIds).Error; err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+		}
+
+		if role == entity.RoleInstructor {
+			instructorId := common.DBConn.Model(&entity.User{}).Select("instructor_id").Where("id = ?", currentUserId)
+
+			taught := make([]uint, 0)
+			if err := common.DBConn.Model(&entity.Instructor{}).Where("id IN (?)", instructorId).Pluck("department_id", &taught).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+			}
+			departmentIds = append(departmentIds, taught...)
+
+			taught = make([]uint, 0)
+			if err := common.DBConn.Model(&entity.Subject{}).Distinct("department_id").
+				Where("id IN (?)", common.DBConn.Model(&entity.InstructorAssignment{}).Select("subject_id").Where("instructor_id IN (?)", instructorId)).
+				Pluck("department_id", &taught).Error; err != nil {
+				return fiber.NewError(fiber.StatusInternalServerError, "Lỗi khi truy vấn cơ sở dữ liệu")
+			}
+			departmentIds = append(departmentIds, taught...)
+		}
+
+		c.Locals("departmentScoped", true)
+		c.Locals("departmentScope", departmentIds)
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	TermStatusPlanned = "planned"
+	TermStatusActive  = "active"
+	TermStatusClosed  = "closed"
+)
+
+// AcademicTerm is one semester of an academic year, Year is the year the academic year starts in.
+type AcademicTerm struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Year      int       `json:"year" gorm:"not null;uniqueIndex:idx_academic_terms_year_semester"`
+	Semester  int8      `json:"semester" gorm:"not null;uniqueIndex:idx_academic_terms_year_semester"`
+	StartDate time.Time `json:"start_date" gorm:"not null"`
+	EndDate   time.Time `json:"end_date" gorm:"not null"`
+	Status    string    `json:"status" gorm:"not null;size:20;default:planned;index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Code names the term like "2024-2025/1", codes sort in chronological order.
+func (t AcademicTerm) Code() string {
+	return fmt.Sprintf("%d-%d/%d", t.Year, t.Year+1, t.Semester)
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// APIKey lets another system call the API without a user, limited to its own permissions and departments.
+// Only the hash of the key is stored, the prefix is kept to tell keys apart.
+type APIKey struct {
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string     `json:"name" gorm:"not null;size:100"`
+	Prefix      string     `json:"prefix" gorm:"not null;size:16"`
+	KeyHash     string     `json:"-" gorm:"unique;not null;size:64"`
+	Permissions []string   `json:"permissions" gorm:"not null;type:text;serializer:json"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `json:"last_used_ip" gorm:"size:45"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+
+	CreatedByID uint `json:"created_by_id" gorm:"not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Departments []Department `json:"departments" gorm:"many2many:api_key_departments"`
+}
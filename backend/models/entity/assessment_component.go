@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// Codes of the three components every subject had before components became configurable.
+const (
+	ComponentCodeProcess = "process"
+	ComponentCodeMidterm = "midterm"
+	ComponentCodeFinal   = "final"
+)
+
+// AssessmentComponent is one weighted part of the grade of a subject, such as labs, quizzes, a project or the final exam.
+// The percentages of the components of a subject sum to 100.
+type AssessmentComponent struct {
+	ID         uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	SubjectID  string `json:"subject_id" gorm:"not null;size:25;uniqueIndex:idx_assessment_components_subject_code"`
+	Code       string `json:"code" gorm:"not null;size:30;uniqueIndex:idx_assessment_components_subject_code"`
+	Name       string `json:"name" gorm:"not null;size:100"`
+	Percentage int8   `json:"percentage" gorm:"not null"`
+	Position   int    `json:"position" gorm:"not null;default:0"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
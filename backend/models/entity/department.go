@@ -9,6 +9,8 @@ type Department struct {
 	Symbol string `json:"symbol" gorm:"size:10;unique;not null"`
 	Name   string `json:"name" gorm:"not null;size:100"`
 
+	GradingScaleID *uint `json:"grading_scale_id" gorm:"index"`
+
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 
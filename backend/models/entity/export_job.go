@@ -0,0 +1,61 @@
+package entity
+
+import "time"
+
+const (
+	ExportJobQueued    = "queued"
+	ExportJobRunning   = "running"
+	ExportJobSucceeded = "succeeded"
+	ExportJobFailed    = "failed"
+	ExportJobCancelled = "cancelled"
+)
+
+const (
+	ExportKindGradesByDepartment = "grades_department"
+	ExportKindGradesByClass      = "grades_class"
+	ExportKindGradesBySubject    = "grades_subject"
+	ExportKindTranscripts        = "transcripts"
+)
+
+// ExportJobParams are the filters of an export, with the department scope of the requester at the time of the request.
+type ExportJobParams struct {
+	DepartmentID  uint   `json:"department_id,omitempty"`
+	ClassID       string `json:"class_id,omitempty"`
+	SubjectID     string `json:"subject_id,omitempty"`
+	StudentID     string `json:"student_id,omitempty"`
+	TermID        uint   `json:"term_id,omitempty"`
+	Scoped        bool   `json:"scoped,omitempty"`
+	DepartmentIds []uint `json:"department_ids,omitempty"`
+}
+
+// ExportJob is an export run in the background. The file is kept until FileExpiresAt and downloaded through a
+// short-lived link, only the hash of the link token is stored.
+type ExportJob struct {
+	ID     uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	Kind   string          `json:"kind" gorm:"not null;size:30"`
+	Params ExportJobParams `json:"params" gorm:"serializer:json;type:text"`
+	Status string          `json:"status" gorm:"not null;size:20;index"`
+	// Done counts the rows written out of Total, Progress is the same as a percentage
+	Done     int    `json:"done" gorm:"not null;default:0"`
+	Total    int    `json:"total" gorm:"not null;default:0"`
+	Progress int    `json:"progress" gorm:"not null;default:0"`
+	Error    string `json:"error" gorm:"size:255"`
+
+	FileName          string     `json:"file_name" gorm:"size:255"`
+	FilePath          string     `json:"-" gorm:"size:255"`
+	FileExpiresAt     *time.Time `json:"file_expires_at"`
+	DownloadTokenHash string     `json:"-" gorm:"size:64;index"`
+	DownloadExpiresAt *time.Time `json:"-"`
+
+	// WorkerID is the API instance running the job, it keeps HeartbeatAt current while the job runs
+	WorkerID    string     `json:"-" gorm:"size:100;index"`
+	HeartbeatAt *time.Time `json:"-"`
+
+	UserID   *uint `json:"user_id" gorm:"index"`
+	APIKeyID *uint `json:"api_key_id" gorm:"index"`
+
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
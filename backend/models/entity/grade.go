@@ -5,15 +5,38 @@ import (
 )
 
 type Grade struct {
-	ID           uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ID uint `json:"id" gorm:"primaryKey;autoIncrement"`
+	// The process, midterm and final columns mirror the components with those codes, grades recorded before
+	// components were configurable only have these
 	ProcessScore float64 `json:"process_score"`
 	MidtermScore float64 `json:"midterm_score"`
 	FinalScore   float64 `json:"final_score"`
+	// The statuses mark a component that was not scored normally, such as an absence or an exemption
+	ProcessStatus string `json:"process_status" gorm:"not null;size:20;default:''"`
+	MidtermStatus string `json:"midterm_status" gorm:"not null;size:20;default:''"`
+	FinalStatus   string `json:"final_status" gorm:"not null;size:20;default:''"`
+	// Attempt numbers the grades of a student in a subject from 1 in the order of their terms, retakes and improvement
+	// attempts take the next number
+	Attempt int `json:"attempt" gorm:"not null;default:1"`
+
+	// Computed by the grading engine when the grade is read while its sheet is a draft. Once the sheet is submitted
+	// the results are stored and ResultLockedAt set, so later changes to the subject or grading scale keep them
+	TotalScore  float64 `json:"total_score"`
+	GPAScore    float64 `json:"gpa_score"`
+	LetterGrade string  `json:"letter_grade" gorm:"not null;size:5;default:''"`
+	Passed      bool    `json:"passed" gorm:"not null;default:false"`
+	// ResultStatus is incomplete, exempt or banned when the component statuses decide the result
+	ResultStatus   string     `json:"result_status" gorm:"not null;size:20;default:''"`
+	ResultLockedAt *time.Time `json:"result_locked_at"`
 
 	SubjectID      string `json:"subject_id" gorm:"not null;size:25;index"`
 	StudentID      string `json:"student_id" gorm:"not null;size:25;index"`
 	ByInstructorID string `json:"by_instructor_id" gorm:"not null;size:25;index"`
+	TermID         *uint  `json:"term_id" gorm:"index"`
+	RegistrationID *uint  `json:"registration_id" gorm:"uniqueIndex"`
 
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Components []GradeComponentScore `json:"components" gorm:"foreignKey:GradeID;constraint:OnDelete:CASCADE"`
 }
@@ -0,0 +1,40 @@
+package entity
+
+import "time"
+
+const (
+	AmendmentStatusPending  = "pending"
+	AmendmentStatusApproved = "approved"
+	AmendmentStatusRejected = "rejected"
+)
+
+// GradeAmendment is a requested change to a locked grade, the scores are applied once another user approves it.
+type GradeAmendment struct {
+	ID      uint `json:"id" gorm:"primaryKey;autoIncrement"`
+	GradeID uint `json:"grade_id" gorm:"not null;index"`
+
+	OldProcessScore  float64               `json:"old_process_score"`
+	OldMidtermScore  float64               `json:"old_midterm_score"`
+	OldFinalScore    float64               `json:"old_final_score"`
+	ProcessScore     float64               `json:"process_score"`
+	MidtermScore     float64               `json:"midterm_score"`
+	FinalScore       float64               `json:"final_score"`
+	OldProcessStatus string                `json:"old_process_status"`
+	OldMidtermStatus string                `json:"old_midterm_status"`
+	OldFinalStatus   string                `json:"old_final_status"`
+	ProcessStatus    string                `json:"process_status"`
+	MidtermStatus    string                `json:"midterm_status"`
+	FinalStatus      string                `json:"final_status"`
+	OldComponents    []GradeComponentScore `json:"old_components" gorm:"serializer:json;type:text"`
+	Components       []GradeComponentScore `json:"components" gorm:"serializer:json;type:text"`
+
+	Reason        string     `json:"reason" gorm:"not null"`
+	Status        string     `json:"status" gorm:"not null;size:20;default:pending;index"`
+	RequestedByID uint       `json:"requested_by_id" gorm:"not null"`
+	ReviewedByID  *uint      `json:"reviewed_by_id"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+	ReviewNote    string     `json:"review_note"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,10 @@
+package entity
+
+// GradeComponentScore is the score of a grade in one assessment component of its subject, matched by the component code.
+type GradeComponentScore struct {
+	ID      uint    `json:"-" gorm:"primaryKey;autoIncrement"`
+	GradeID uint    `json:"-" gorm:"not null;uniqueIndex:idx_grade_component_scores_grade_code"`
+	Code    string  `json:"code" gorm:"not null;size:30;uniqueIndex:idx_grade_component_scores_grade_code"`
+	Score   float64 `json:"score"`
+	Status  string  `json:"status" gorm:"not null;size:20;default:''"`
+}
@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	GradeHistoryCreate = "create"
+	GradeHistoryUpdate = "update"
+	GradeHistoryDelete = "delete"
+	GradeHistoryAmend  = "amend"
+)
+
+var ErrGradeHistoryImmutable = errors.New("grade history is append only")
+
+// GradeHistory is one change of a grade. The rows are only ever inserted, they keep the state of the grade
+// around the change so the grades of a student can be rebuilt at any point in time, also after a delete.
+type GradeHistory struct {
+	ID      uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	GradeID uint   `json:"grade_id" gorm:"not null;index"`
+	Action  string `json:"action" gorm:"not null;size:20"`
+
+	SubjectID      string    `json:"subject_id" gorm:"not null;size:25"`
+	StudentID      string    `json:"student_id" gorm:"not null;size:25;index"`
+	ByInstructorID string    `json:"by_instructor_id" gorm:"not null;size:25"`
+	TermID         *uint     `json:"term_id"`
+	RegistrationID *uint     `json:"registration_id"`
+	Attempt        int       `json:"attempt"`
+	GradeCreatedAt time.Time `json:"grade_created_at"`
+
+	// The old scores and statuses are empty on create and the new ones on delete
+	OldProcessScore  *float64 `json:"old_process_score"`
+	OldMidtermScore  *float64 `json:"old_midterm_score"`
+	OldFinalScore    *float64 `json:"old_final_score"`
+	NewProcessScore  *float64 `json:"new_process_score"`
+	NewMidtermScore  *float64 `json:"new_midterm_score"`
+	NewFinalScore    *float64 `json:"new_final_score"`
+	OldProcessStatus string   `json:"old_process_status"`
+	OldMidtermStatus string   `json:"old_midterm_status"`
+	OldFinalStatus   string   `json:"old_final_status"`
+	NewProcessStatus string   `json:"new_process_status"`
+	NewMidtermStatus string   `json:"new_midterm_status"`
+	NewFinalStatus   string   `json:"new_final_status"`
+	// The component scores around the change, the columns above mirror the process, midterm and final components
+	OldComponents []GradeComponentScore `json:"old_components" gorm:"serializer:json;type:text"`
+	NewComponents []GradeComponentScore `json:"new_components" gorm:"serializer:json;type:text"`
+
+	UserID   *uint  `json:"user_id"`
+	APIKeyID *uint  `json:"api_key_id"`
+	IP       string `json:"ip" gorm:"size:45"`
+	Reason   string `json:"reason"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+func (h *GradeHistory) BeforeUpdate(tx *gorm.DB) error {
+	return ErrGradeHistoryImmutable
+}
+
+func (h *GradeHistory) BeforeDelete(tx *gorm.DB) error {
+	return ErrGradeHistoryImmutable
+}
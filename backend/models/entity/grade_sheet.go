@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+const (
+	GradeSheetStatusDraft     = "draft"
+	GradeSheetStatusSubmitted = "submitted"
+	GradeSheetStatusApproved  = "approved"
+	GradeSheetStatusPublished = "published"
+)
+
+// GradeSheet tracks the grades of one subject offering, the subject taught in a term, through entry by the
+// instructor, approval by the department and publishing. Published grades are locked and only change through
+// an approved GradeAmendment.
+type GradeSheet struct {
+	ID        uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	SubjectID string `json:"subject_id" gorm:"not null;size:25;uniqueIndex:idx_grade_sheets_subject_term"`
+	TermID    uint   `json:"term_id" gorm:"not null;uniqueIndex:idx_grade_sheets_subject_term"`
+	Status    string `json:"status" gorm:"not null;size:20;default:draft;index"`
+	// ReturnReason explains why the department sent the sheet back to draft
+	ReturnReason string `json:"return_reason"`
+
+	SubmittedByID *uint      `json:"submitted_by_id"`
+	SubmittedAt   *time.Time `json:"submitted_at"`
+	ApprovedByID  *uint      `json:"approved_by_id"`
+	ApprovedAt    *time.Time `json:"approved_at"`
+	PublishedByID *uint      `json:"published_by_id"`
+	PublishedAt   *time.Time `json:"published_at"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+)
+
+// GradingScale converts a total score into a letter grade, a 4-point value and a pass flag. It is attached to a
+// department and can be overridden per subject.
+type GradingScale struct {
+	ID           uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name         string  `json:"name" gorm:"not null;size:100;unique"`
+	RoundingStep float64 `json:"rounding_step" gorm:"not null;default:0.1"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Bands []GradingScaleBand `json:"bands" gorm:"foreignKey:GradingScaleID;constraint:OnDelete:CASCADE"`
+}
+
+// GradingScaleBand covers every total score from MinScore up to the next band of the scale.
+type GradingScaleBand struct {
+	ID       uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Letter   string  `json:"letter" gorm:"not null;size:5"`
+	MinScore float64 `json:"min_score" gorm:"not null"`
+	Point    float64 `json:"point" gorm:"not null"`
+	Passed   bool    `json:"passed" gorm:"not null"`
+
+	GradingScaleID uint `json:"grading_scale_id" gorm:"not null;index"`
+}
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// Invitation lets a new user create an account with the role chosen by an admin, only the token hash is stored.
+type Invitation struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	TokenHash  string     `json:"-" gorm:"unique;not null;size:64"`
+	Email      string     `json:"email" gorm:"not null;size:100;index"`
+	Role       string     `json:"role" gorm:"not null;size:20"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+
+	InvitedByID uint  `json:"invited_by_id" gorm:"not null"`
+	UserID      *uint `json:"user_id"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// LoginThrottle counts the failed logins of one username or one IP address.
+type LoginThrottle struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Key          string     `json:"key" gorm:"unique;not null;size:100"`
+	Failures     int        `json:"failures" gorm:"not null"`
+	LastFailedAt time.Time  `json:"last_failed_at" gorm:"not null"`
+	LockedUntil  *time.Time `json:"locked_until" gorm:"index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
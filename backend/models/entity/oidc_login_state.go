@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// OIDCLoginState keeps the nonce and PKCE verifier of a login between the redirect to the identity provider and the callback.
+type OIDCLoginState struct {
+	ID           string    `json:"-" gorm:"primaryKey;size:64"`
+	Nonce        string    `json:"-" gorm:"not null;size:64"`
+	CodeVerifier string    `json:"-" gorm:"not null;size:128"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null;index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_states"
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// PasswordResetToken is a single-use token mailed to the user, only its hash is stored.
+type PasswordResetToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	TokenHash string     `json:"-" gorm:"unique;not null;size:64"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+
+	UserID uint `json:"user_id" gorm:"not null;index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// RecoveryCode is a single-use fallback for a lost authenticator, only its hash is stored.
+type RecoveryCode struct {
+	ID       uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CodeHash string     `json:"-" gorm:"not null;size:64"`
+	UsedAt   *time.Time `json:"used_at"`
+
+	UserID uint `json:"user_id" gorm:"not null;index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
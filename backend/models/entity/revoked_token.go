@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// RevokedToken is an access token revoked before it expires, kept until ExpiresAt since the token is rejected
+// on its own after that.
+type RevokedToken struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:32"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -5,14 +5,35 @@ import (
 	"time"
 )
 
+const (
+	RoleAdmin             = "admin"
+	RoleDepartmentManager = "department_manager"
+	RoleInstructor        = "instructor"
+	RoleStudent           = "student"
+)
+
 type User struct {
-	ID        uint   `json:"id" gorm:"primaryKey;autoIncrement"`
-	FirstName string `json:"first_name" gorm:"not null;size:50"`
-	LastName  string `json:"last_name" gorm:"not null;size:50"`
-	UserName  string `json:"username" gorm:"unique;not null;size:30"`
-	Password  string `json:"-" gorm:"not null;size:255"`
+	ID        uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	FirstName string  `json:"first_name" gorm:"not null;size:50"`
+	LastName  string  `json:"last_name" gorm:"not null;size:50"`
+	UserName  string  `json:"username" gorm:"unique;not null;size:30"`
+	Password  string  `json:"-" gorm:"not null;size:255"`
+	Email     *string `json:"email" gorm:"size:100;uniqueIndex"`
+	Role      string  `json:"role" gorm:"not null;size:20;default:student"`
+
+	TOTPSecret   string `json:"-" gorm:"size:64"`
+	TOTPEnabled  bool   `json:"totp_enabled" gorm:"not null;default:false"`
+	TOTPRequired bool   `json:"totp_required" gorm:"not null;default:false"`
+	TOTPLastStep int64  `json:"-" gorm:"not null;default:0"`
+
+	InstructorID *string `json:"instructor_id" gorm:"size:25;uniqueIndex"`
+	StudentID    *string `json:"student_id" gorm:"size:25;uniqueIndex"`
 
 	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	Departments []Department `json:"departments" gorm:"many2many:user_departments"`
+	Instructor  *Instructor  `json:"instructor,omitempty" gorm:"foreignKey:InstructorID"`
+	Student     *Student     `json:"student,omitempty" gorm:"foreignKey:StudentID"`
 }
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// UserSession is one login of a user, its refresh token rotates on every refresh
+// and revoking it invalidates every access token issued for it.
+type UserSession struct {
+	ID               string     `json:"id" gorm:"primaryKey;size:32"`
+	RefreshTokenHash string     `json:"-" gorm:"not null;size:64"`
+	IP               string     `json:"ip" gorm:"size:45"`
+	UserAgent        string     `json:"user_agent" gorm:"size:255"`
+	ExpiresAt        time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+
+	UserID uint `json:"user_id" gorm:"not null;index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
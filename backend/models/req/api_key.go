@@ -0,0 +1,10 @@
+package req
+
+import "time"
+
+type APIKeyCreate struct {
+	Name          string     `json:"name" validate:"required,min=3,max=100"`
+	Permissions   []string   `json:"permissions" validate:"required,min=1"`
+	DepartmentIDs []uint     `json:"department_ids"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+}
@@ -3,9 +3,11 @@ package req
 type AssignmentCreate struct {
 	SubjectID    string `json:"subject_id" validate:"required"`
 	InstructorID string `json:"instructor_id" validate:"required"`
+	TermID       uint   `json:"term_id"`
 }
 
 type AssignmentUpdateById struct {
 	SubjectID    string `json:"subject_id" validate:"required"`
 	InstructorID string `json:"instructor_id" validate:"required"`
+	TermID       uint   `json:"term_id"`
 }
@@ -9,5 +9,50 @@ type AuthRegister struct {
 	FirstName string `json:"first_name" validate:"required,min=3,max=50"`
 	LastName  string `json:"last_name" validate:"required,min=3,max=50"`
 	UserName  string `json:"username" validate:"required,min=3,max=30"`
+	Email     string `json:"email" validate:"omitempty,email,max=100"`
 	Password  string `json:"password" validate:"required,min=8"`
 }
+
+type AuthRefresh struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type AuthTwoFactorEnroll struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+type AuthTwoFactorVerify struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type AuthTwoFactorCode struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type AuthPasswordChange struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type AuthPasswordForgot struct {
+	UserName string `json:"username" validate:"required,min=3,max=30"`
+}
+
+type AuthPasswordReset struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+type AuthInvitationAccept struct {
+	Token     string `json:"token" validate:"required"`
+	FirstName string `json:"first_name" validate:"required,min=3,max=50"`
+	LastName  string `json:"last_name" validate:"required,min=3,max=50"`
+	UserName  string `json:"username" validate:"required,min=3,max=30"`
+	Password  string `json:"password" validate:"required,min=8"`
+}
+
+type AuthOIDCCallback struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
@@ -0,0 +1,12 @@
+package req
+
+// The target of the export depends on its kind: department_id, class_id or subject_id for the grades, student_id or
+// class_id for the transcripts
+type ExportJobCreate struct {
+	Kind         string `json:"kind" validate:"required,oneof=grades_department grades_class grades_subject transcripts"`
+	DepartmentID uint   `json:"department_id"`
+	ClassID      string `json:"class_id" validate:"max=25"`
+	SubjectID    string `json:"subject_id" validate:"max=25"`
+	StudentID    string `json:"student_id" validate:"max=25"`
+	TermID       uint   `json:"term_id"`
+}
@@ -1,17 +1,84 @@
 package req
 
+type GradeComponentScore struct {
+	Code   string  `json:"code" validate:"required,max=30"`
+	Score  float64 `json:"score" validate:"number,gte=0,lte=10"`
+	Status string  `json:"status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+}
+
 type GradeCreate struct {
 	ProcessScore float64 `json:"process_score" validate:"number,gte=0,lte=10" `
 	MidtermScore float64 `json:"midterm_score" validate:"number,gte=0,lte=10"`
 	FinalScore   float64 `json:"final_score" validate:"number,gte=0,lte=10"`
 
+	ProcessStatus string `json:"process_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+	MidtermStatus string `json:"midterm_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+	FinalStatus   string `json:"final_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+
+	// Components gives the scores per assessment component of the subject, instead of the process, midterm and final scores
+	Components []GradeComponentScore `json:"components" validate:"omitempty,dive"`
+
 	SubjectID      string `json:"subject_id" validate:"required"`
 	StudentID      string `json:"student_id" validate:"required"`
-	ByInstructorID string `json:"by_instructor_id" validate:"required"`
+	ByInstructorID string `json:"by_instructor_id"`
+	TermID         uint   `json:"term_id"`
 }
 
 type GradeUpdateById struct {
 	ProcessScore float64 `json:"process_score" validate:"number,gte=0,lte=10" `
 	MidtermScore float64 `json:"midterm_score" validate:"number,gte=0,lte=10"`
 	FinalScore   float64 `json:"final_score" validate:"number,gte=0,lte=10"`
+
+	ProcessStatus string `json:"process_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+	MidtermStatus string `json:"midterm_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+	FinalStatus   string `json:"final_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+
+	// Components gives the scores per assessment component of the subject, instead of the process, midterm and final scores
+	Components []GradeComponentScore `json:"components" validate:"omitempty,dive"`
+	Reason     string                `json:"reason" validate:"max=1000"`
+}
+
+type GradeAmendmentCreate struct {
+	ProcessScore float64 `json:"process_score" validate:"number,gte=0,lte=10"`
+	MidtermScore float64 `json:"midterm_score" validate:"number,gte=0,lte=10"`
+	FinalScore   float64 `json:"final_score" validate:"number,gte=0,lte=10"`
+
+	ProcessStatus string `json:"process_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+	MidtermStatus string `json:"midterm_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+	FinalStatus   string `json:"final_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+
+	// Components gives the scores per assessment component of the subject, instead of the process, midterm and final scores
+	Components []GradeComponentScore `json:"components" validate:"omitempty,dive"`
+	Reason     string                `json:"reason" validate:"required,min=10,max=1000"`
+}
+
+type GradeAmendmentReview struct {
+	Note string `json:"note" validate:"max=1000"`
+}
+
+type GradeSheetReturn struct {
+	Reason string `json:"reason" validate:"required,min=5,max=1000"`
+}
+
+type GradeBatchRow struct {
+	StudentID string `json:"student_id" validate:"required"`
+
+	ProcessScore float64 `json:"process_score" validate:"number,gte=0,lte=10"`
+	MidtermScore float64 `json:"midterm_score" validate:"number,gte=0,lte=10"`
+	FinalScore   float64 `json:"final_score" validate:"number,gte=0,lte=10"`
+
+	ProcessStatus string `json:"process_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+	MidtermStatus string `json:"midterm_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+	FinalStatus   string `json:"final_status" validate:"omitempty,oneof=absent_excused absent_unexcused exempt incomplete banned"`
+
+	Components []GradeComponentScore `json:"components" validate:"omitempty,dive"`
+}
+
+// The rows are validated one by one so that every invalid row is reported
+type GradeBatch struct {
+	SubjectID      string          `json:"subject_id" validate:"required"`
+	ByInstructorID string          `json:"by_instructor_id"`
+	TermID         uint            `json:"term_id"`
+	Reason         string          `json:"reason" validate:"max=1000"`
+	Rows           []GradeBatchRow `json:"rows" validate:"required"`
 }
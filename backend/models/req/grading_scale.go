@@ -0,0 +1,25 @@
+package req
+
+type GradingScaleBand struct {
+	Letter   string  `json:"letter" validate:"required,max=5"`
+	MinScore float64 `json:"min_score" validate:"number,gte=0,lte=10"`
+	Point    float64 `json:"point" validate:"number,gte=0,lte=4"`
+	Passed   bool    `json:"passed" validate:"boolean"`
+}
+
+type GradingScaleCreate struct {
+	Name         string             `json:"name" validate:"required,min=3,max=100"`
+	RoundingStep float64            `json:"rounding_step" validate:"required,gte=0.01,lte=1"`
+	Bands        []GradingScaleBand `json:"bands" validate:"required,min=1,dive"`
+}
+
+type GradingScaleUpdateById struct {
+	Name         string             `json:"name" validate:"required,min=3,max=100"`
+	RoundingStep float64            `json:"rounding_step" validate:"required,gte=0.01,lte=1"`
+	Bands        []GradingScaleBand `json:"bands" validate:"required,min=1,dive"`
+}
+
+// UpdateGradingScaleById attaches a grading scale to a department or subject, null detaches it.
+type UpdateGradingScaleById struct {
+	GradingScaleID *uint `json:"grading_scale_id"`
+}
@@ -3,9 +3,11 @@ package req
 type RegistrationCreate struct {
 	SubjectID string `json:"subject_id" validate:"required"`
 	StudentID string `json:"student_id" validate:"required"`
+	TermID    uint   `json:"term_id"`
 }
 
 type RegistrationUpdateById struct {
 	SubjectID string `json:"subject_id" validate:"required"`
 	StudentID string `json:"student_id" validate:"required"`
+	TermID    uint   `json:"term_id"`
 }
@@ -1,20 +1,29 @@
 package req
 
+type SubjectComponent struct {
+	Code       string `json:"code" validate:"required,max=30"`
+	Name       string `json:"name" validate:"required,max=100"`
+	Percentage int8   `json:"percentage" validate:"required,gte=1,lte=100"`
+}
+
+// The process, midterm and final percentages are used when no components are given
 type SubjectCreate struct {
-	Name              string `json:"name" validate:"required,max=100"`
-	Credits           int8   `json:"credits" validate:"required,gte=1,lte=128"`
-	ProcessPercentage int8   `json:"process_percentage" validate:"gte=0,lte=50"`
-	MidtermPercentage int8   `json:"midterm_percentage" validate:"gte=0,lte=50"`
-	FinalPercentage   int8   `json:"final_percentage" validate:"required,gte=50,lte=100"`
-	DepartmentID      uint   `json:"department_id" validate:"required"`
+	Name              string             `json:"name" validate:"required,max=100"`
+	Credits           int8               `json:"credits" validate:"required,gte=1,lte=128"`
+	ProcessPercentage int8               `json:"process_percentage" validate:"gte=0,lte=100"`
+	MidtermPercentage int8               `json:"midterm_percentage" validate:"gte=0,lte=100"`
+	FinalPercentage   int8               `json:"final_percentage" validate:"gte=0,lte=100"`
+	Components        []SubjectComponent `json:"components" validate:"omitempty,dive"`
+	DepartmentID      uint               `json:"department_id" validate:"required"`
 }
 
 type SubjectUpdateById struct {
-	Name              string `json:"name" validate:"required,max=100"`
-	Credits           int8   `json:"credits" validate:"required,gte=1,lte=128"`
-	ProcessPercentage int8   `json:"process_percentage" validate:"required,gte=0,lte=50"`
-	MidtermPercentage int8   `json:"midterm_percentage" validate:"required,gte=0,lte=50"`
-	FinalPercentage   int8   `json:"final_percentage" validate:"required,gte=50,lte=100"`
+	Name              string             `json:"name" validate:"required,max=100"`
+	Credits           int8               `json:"credits" validate:"required,gte=1,lte=128"`
+	ProcessPercentage int8               `json:"process_percentage" validate:"gte=0,lte=100"`
+	MidtermPercentage int8               `json:"midterm_percentage" validate:"gte=0,lte=100"`
+	FinalPercentage   int8               `json:"final_percentage" validate:"gte=0,lte=100"`
+	Components        []SubjectComponent `json:"components" validate:"omitempty,dive"`
 }
 
 type SubjectDeleteByListId struct {
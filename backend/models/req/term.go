@@ -0,0 +1,17 @@
+package req
+
+import "time"
+
+type TermCreate struct {
+	Year      int       `json:"year" validate:"required,gte=2000,lte=2100"`
+	Semester  int8      `json:"semester" validate:"required,gte=1,lte=3"`
+	StartDate time.Time `json:"start_date" validate:"required"`
+	EndDate   time.Time `json:"end_date" validate:"required"`
+	Status    string    `json:"status" validate:"required,oneof=planned active closed"`
+}
+
+type TermUpdateById struct {
+	StartDate time.Time `json:"start_date" validate:"required"`
+	EndDate   time.Time `json:"end_date" validate:"required"`
+	Status    string    `json:"status" validate:"required,oneof=planned active closed"`
+}
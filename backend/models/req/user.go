@@ -0,0 +1,35 @@
+package req
+
+type UserUpdateDepartmentsById struct {
+	DepartmentIDs []uint `json:"department_ids"`
+}
+
+type UserUpdateInstructorById struct {
+	InstructorID string `json:"instructor_id"`
+}
+
+type UserUpdateStudentById struct {
+	StudentID string `json:"student_id"`
+}
+
+type UserUpdateTwoFactorById struct {
+	Required bool `json:"required" validate:"boolean"`
+}
+
+type UserCreate struct {
+	FirstName string `json:"first_name" validate:"required,min=3,max=50"`
+	LastName  string `json:"last_name" validate:"required,min=3,max=50"`
+	UserName  string `json:"username" validate:"required,min=3,max=30"`
+	Email     string `json:"email" validate:"omitempty,email,max=100"`
+	Password  string `json:"password" validate:"required,min=8"`
+	Role      string `json:"role" validate:"required"`
+}
+
+type UserUpdateRoleById struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type InvitationCreate struct {
+	Email string `json:"email" validate:"required,email,max=100"`
+	Role  string `json:"role" validate:"required"`
+}
@@ -10,15 +10,22 @@ func SetupRouter(app *fiber.App) {
 	publicAPIRoute := app.Group("api")
 	publicAPIRoute.Add("GET", "metrics", monitor.New(monitor.Config{Title: "Quan Ly Diem Sinh Vien Metrics"}))
 	authRouter(publicAPIRoute)
+	jobsPublicRouter(publicAPIRoute)
 
-	privateAPIRoute := app.Group("api", middleware.Protected())
+	privateAPIRoute := app.Group("api", middleware.Protected(), middleware.DepartmentScope())
 	usersRouter(privateAPIRoute)
+	apiKeysRouter(privateAPIRoute)
+	meRouter(privateAPIRoute)
 	departmentsRouter(privateAPIRoute)
 	subjectsRouter(privateAPIRoute)
 	classesRouter(privateAPIRoute)
 	instructorsRouter(privateAPIRoute)
 	studentsRouter(privateAPIRoute)
 	gradesRouter(privateAPIRoute)
+	gradeSheetsRouter(privateAPIRoute)
 	assignmentsRouter(privateAPIRoute)
 	registrationsRouter(privateAPIRoute)
+	gradingScalesRouter(privateAPIRoute)
+	termsRouter(privateAPIRoute)
+	jobsRouter(privateAPIRoute)
 }
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
+	"qldiemsv/controllers"
+	"qldiemsv/middleware"
+)
+
+func apiKeysRouter(r fiber.Router) {
+	apiKeysRoute := r.Group("api-keys")
+
+	apiKeysRoute.Add("GET", "", middleware.Permission(common.PermAPIKeyManage), controllers.APIKeyGetAll)
+	apiKeysRoute.Add("POST", "", middleware.Permission(common.PermAPIKeyManage), controllers.APIKeyCreate)
+	apiKeysRoute.Add("DELETE", ":id", middleware.Permission(common.PermAPIKeyManage), controllers.APIKeyDeleteById)
+}
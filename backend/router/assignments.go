@@ -2,18 +2,20 @@ package router
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
 	"qldiemsv/controllers"
+	"qldiemsv/middleware"
 )
 
 func assignmentsRouter(r fiber.Router) {
 	assignmentsRoute := r.Group("assignments")
 
-	assignmentsRoute.Add("GET", "", controllers.AssignmentGetAll)
-	assignmentsRoute.Add("GET", "department/:id", controllers.AssignmentGetAllByDepartmentId)
-	assignmentsRoute.Add("GET", "instructor/:name", controllers.AssignmentGetAllInstructorByFullName)
-	assignmentsRoute.Add("POST", "", controllers.AssignmentCreate)
-	assignmentsRoute.Add("PUT", ":id", controllers.AssignmentUpdateById)
+	assignmentsRoute.Add("GET", "", middleware.Permission(common.PermAssignmentRead), controllers.AssignmentGetAll)
+	assignmentsRoute.Add("GET", "department/:id", middleware.Permission(common.PermAssignmentRead), controllers.AssignmentGetAllByDepartmentId)
+	assignmentsRoute.Add("GET", "instructor/:name", middleware.Permission(common.PermAssignmentRead), controllers.AssignmentGetAllInstructorByFullName)
+	assignmentsRoute.Add("POST", "", middleware.Permission(common.PermAssignmentWrite), controllers.AssignmentCreate)
+	assignmentsRoute.Add("PUT", ":id", middleware.Permission(common.PermAssignmentWrite), controllers.AssignmentUpdateById)
 	//assignmentsRoute.Add("DELETE", "", controllers.AssignmentDeleteAll)
 	//assignmentsRoute.Add("DELETE", "list", controllers.AssignmentDeleteByListId)
-	assignmentsRoute.Add("DELETE", ":id", controllers.AssignmentDeleteById)
+	assignmentsRoute.Add("DELETE", ":id", middleware.Permission(common.PermAssignmentWrite), controllers.AssignmentDeleteById)
 }
@@ -11,5 +11,26 @@ func authRouter(r fiber.Router) {
 
 	authRoute.Add("POST", "login", controllers.AuthLogin)
 	authRoute.Add("POST", "register", controllers.AuthRegister)
+	authRoute.Add("POST", "invitations/accept", controllers.AuthInvitationAccept)
+	authRoute.Add("POST", "refresh", controllers.AuthRefresh)
 	authRoute.Add("GET", "verify", middleware.Protected(), controllers.AuthVerify)
+	authRoute.Add("POST", "logout", middleware.Protected(), controllers.AuthLogout)
+	authRoute.Add("POST", "logout/all", middleware.Protected(), controllers.AuthLogoutAll)
+
+	oidcRoute := authRoute.Group("oidc")
+	oidcRoute.Add("GET", "login", controllers.AuthOIDCLogin)
+	oidcRoute.Add("POST", "callback", controllers.AuthOIDCCallback)
+
+	passwordRoute := authRoute.Group("password")
+	passwordRoute.Add("POST", "change", middleware.Protected(), controllers.AuthPasswordChange)
+	passwordRoute.Add("POST", "forgot", controllers.AuthPasswordForgot)
+	passwordRoute.Add("POST", "reset", controllers.AuthPasswordReset)
+
+	twoFactorRoute := authRoute.Group("2fa")
+	twoFactorRoute.Add("POST", "enroll", controllers.AuthTwoFactorEnroll)
+	twoFactorRoute.Add("POST", "verify", controllers.AuthTwoFactorVerify)
+	twoFactorRoute.Add("POST", "setup", middleware.Protected(), controllers.AuthTwoFactorSetup)
+	twoFactorRoute.Add("POST", "enable", middleware.Protected(), controllers.AuthTwoFactorEnable)
+	twoFactorRoute.Add("POST", "disable", middleware.Protected(), controllers.AuthTwoFactorDisable)
+	twoFactorRoute.Add("POST", "recovery-codes", middleware.Protected(), controllers.AuthTwoFactorRecoveryCodes)
 }
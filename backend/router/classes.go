@@ -2,18 +2,20 @@ package router
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
 	"qldiemsv/controllers"
+	"qldiemsv/middleware"
 )
 
 func classesRouter(r fiber.Router) {
 	classesRoute := r.Group("classes")
 
-	classesRoute.Add("GET", "", controllers.ClassGetAll)
-	classesRoute.Add("GET", ":id", controllers.ClassGetById)
-	classesRoute.Add("POST", "", controllers.ClassCreate)
-	classesRoute.Add("PUT", ":id", controllers.ClassUpdateById)
-	classesRoute.Add("DELETE", "", controllers.ClassDeleteAll)
-	classesRoute.Add("DELETE", "list", controllers.ClassDeleteByListId)
-	classesRoute.Add("DELETE", ":id", controllers.ClassDeleteById)
-	classesRoute.Add("GET", "/department/:departmentID", controllers.GetClassesByDepartmentID)
+	classesRoute.Add("GET", "", middleware.Permission(common.PermClassRead), controllers.ClassGetAll)
+	classesRoute.Add("GET", ":id", middleware.Permission(common.PermClassRead), controllers.ClassGetById)
+	classesRoute.Add("POST", "", middleware.Permission(common.PermClassWrite), controllers.ClassCreate)
+	classesRoute.Add("PUT", ":id", middleware.Permission(common.PermClassWrite), controllers.ClassUpdateById)
+	classesRoute.Add("DELETE", "", middleware.Permission(common.PermClassWrite, common.PermBulkDelete), controllers.ClassDeleteAll)
+	classesRoute.Add("DELETE", "list", middleware.Permission(common.PermClassWrite, common.PermBulkDelete), controllers.ClassDeleteByListId)
+	classesRoute.Add("DELETE", ":id", middleware.Permission(common.PermClassWrite), controllers.ClassDeleteById)
+	classesRoute.Add("GET", "/department/:departmentID", middleware.Permission(common.PermClassRead), controllers.GetClassesByDepartmentID)
 }
@@ -2,21 +2,24 @@ package router
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
 	"qldiemsv/controllers"
+	"qldiemsv/middleware"
 )
 
 func departmentsRouter(r fiber.Router) {
 	departmentsRoute := r.Group("departments")
 
 	// [GET] /api/departments
-	departmentsRoute.Add("GET", "", controllers.DepartmentGetAll)
-	departmentsRoute.Add("GET", ":id", controllers.DepartmentGetById)
+	departmentsRoute.Add("GET", "", middleware.Permission(common.PermDepartmentRead), controllers.DepartmentGetAll)
+	departmentsRoute.Add("GET", ":id", middleware.Permission(common.PermDepartmentRead), controllers.DepartmentGetById)
 	// [POST] /api/departments
-	departmentsRoute.Add("POST", "", controllers.DepartmentCreate)
+	departmentsRoute.Add("POST", "", middleware.Permission(common.PermDepartmentWrite), controllers.DepartmentCreate)
 	// [PUT] /api/departments
-	departmentsRoute.Add("PUT", ":id", controllers.DepartmentUpdateById)
+	departmentsRoute.Add("PUT", ":id", middleware.Permission(common.PermDepartmentWrite), controllers.DepartmentUpdateById)
+	departmentsRoute.Add("PUT", ":id/grading-scale", middleware.Permission(common.PermDepartmentWrite, common.PermGradingScaleRead), controllers.DepartmentUpdateGradingScaleById)
 	// [DELETE] /api/departments
-	departmentsRoute.Add("DELETE", "", controllers.DepartmentDeleteAll)
-	departmentsRoute.Add("DELETE", "list", controllers.DepartmentDeleteByListId)
-	departmentsRoute.Add("DELETE", ":id", controllers.DepartmentDeleteById)
+	departmentsRoute.Add("DELETE", "", middleware.Permission(common.PermDepartmentWrite, common.PermBulkDelete), controllers.DepartmentDeleteAll)
+	departmentsRoute.Add("DELETE", "list", middleware.Permission(common.PermDepartmentWrite, common.PermBulkDelete), controllers.DepartmentDeleteByListId)
+	departmentsRoute.Add("DELETE", ":id", middleware.Permission(common.PermDepartmentWrite), controllers.DepartmentDeleteById)
 }
@@ -0,0 +1,25 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
+	"qldiemsv/controllers"
+	"qldiemsv/middleware"
+)
+
+func gradeSheetsRouter(r fiber.Router) {
+	gradeSheetsRoute := r.Group("grade-sheets")
+
+	gradeSheetsRoute.Add("GET", "", middleware.Permission(common.PermGradeRead), controllers.GradeSheetGetAll)
+	gradeSheetsRoute.Add("GET", ":id", middleware.Permission(common.PermGradeRead), controllers.GradeSheetGetById)
+	gradeSheetsRoute.Add("POST", ":id/submit", middleware.Permission(common.PermGradeWrite), controllers.GradeSheetSubmitById)
+	gradeSheetsRoute.Add("POST", ":id/approve", middleware.Permission(common.PermGradeApprove), controllers.GradeSheetApproveById)
+	gradeSheetsRoute.Add("POST", ":id/return", middleware.Permission(common.PermGradeApprove), controllers.GradeSheetReturnById)
+	gradeSheetsRoute.Add("POST", ":id/publish", middleware.Permission(common.PermGradeApprove), controllers.GradeSheetPublishById)
+
+	gradeAmendmentsRoute := r.Group("grade-amendments")
+
+	gradeAmendmentsRoute.Add("GET", "", middleware.Permission(common.PermGradeApprove), controllers.GradeAmendmentGetAll)
+	gradeAmendmentsRoute.Add("POST", ":id/approve", middleware.Permission(common.PermGradeApprove), controllers.GradeAmendmentApproveById)
+	gradeAmendmentsRoute.Add("POST", ":id/reject", middleware.Permission(common.PermGradeApprove), controllers.GradeAmendmentRejectById)
+}
@@ -2,18 +2,26 @@ package router
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
 	"qldiemsv/controllers"
+	"qldiemsv/middleware"
 )
 
 func gradesRouter(r fiber.Router) {
 	gradesRoute := r.Group("grades")
 
-	gradesRoute.Add("GET", "", controllers.GradeGetList)
-	gradesRoute.Add("GET", "department/:id", controllers.GradeGetAllByDepartmentId)
-	gradesRoute.Add("GET", "export", controllers.GradeExportExcelList)
-	gradesRoute.Add("GET", "export/department/:id", controllers.GradeExportExcelByDepartmentId)
-	gradesRoute.Add("GET", ":id", controllers.GradeGetById)
-	gradesRoute.Add("POST", "", controllers.GradeCreate)
-	gradesRoute.Add("PUT", ":id", controllers.GradeUpdateById)
-	gradesRoute.Add("DELETE", ":id", controllers.GradeDeleteById)
+	gradesRoute.Add("GET", "", middleware.Permission(common.PermGradeRead), controllers.GradeGetList)
+	gradesRoute.Add("GET", "department/:id", middleware.Permission(common.PermGradeRead), controllers.GradeGetAllByDepartmentId)
+	gradesRoute.Add("GET", "export", middleware.Permission(common.PermGradeRead, common.PermGradeExport), controllers.GradeExportExcelList)
+	gradesRoute.Add("GET", "export/department/:id", middleware.Permission(common.PermGradeRead, common.PermGradeExport), controllers.GradeExportExcelByDepartmentId)
+	gradesRoute.Add("GET", "template/subject/:id", middleware.Permission(common.PermGradeRead), controllers.GradeTemplateBySubjectId)
+	gradesRoute.Add("GET", ":id", middleware.Permission(common.PermGradeRead), controllers.GradeGetById)
+	gradesRoute.Add("POST", "", middleware.Permission(common.PermGradeWrite), controllers.GradeCreate)
+	gradesRoute.Add("POST", "batch", middleware.Permission(common.PermGradeWrite), controllers.GradeBatchSave)
+	gradesRoute.Add("POST", "import/subject/:id", middleware.Permission(common.PermGradeWrite), controllers.GradeImportBySubjectId)
+	gradesRoute.Add("PUT", ":id", middleware.Permission(common.PermGradeWrite), controllers.GradeUpdateById)
+	gradesRoute.Add("DELETE", ":id", middleware.Permission(common.PermGradeWrite), controllers.GradeDeleteById)
+	gradesRoute.Add("GET", ":id/history", middleware.Permission(common.PermGradeRead), controllers.GradeHistoryGetById)
+	gradesRoute.Add("GET", ":id/amendments", middleware.Permission(common.PermGradeRead), controllers.GradeAmendmentGetAllByGradeId)
+	gradesRoute.Add("POST", ":id/amendments", middleware.Permission(common.PermGradeWrite), controllers.GradeAmendmentCreate)
 }
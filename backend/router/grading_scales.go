@@ -0,0 +1,19 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
+	"qldiemsv/controllers"
+	"qldiemsv/middleware"
+)
+
+func gradingScalesRouter(r fiber.Router) {
+	gradingScalesRoute := r.Group("grading-scales")
+
+	gradingScalesRoute.Add("GET", "", middleware.Permission(common.PermGradingScaleRead), controllers.GradingScaleGetAll)
+	gradingScalesRoute.Add("GET", "default", middleware.Permission(common.PermGradingScaleRead), controllers.GradingScaleGetDefault)
+	gradingScalesRoute.Add("GET", ":id", middleware.Permission(common.PermGradingScaleRead), controllers.GradingScaleGetById)
+	gradingScalesRoute.Add("POST", "", middleware.Permission(common.PermGradingScaleWrite), controllers.GradingScaleCreate)
+	gradingScalesRoute.Add("PUT", ":id", middleware.Permission(common.PermGradingScaleWrite), controllers.GradingScaleUpdateById)
+	gradingScalesRoute.Add("DELETE", ":id", middleware.Permission(common.PermGradingScaleWrite), controllers.GradingScaleDeleteById)
+}
@@ -2,23 +2,27 @@ package router
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
 	"qldiemsv/controllers"
+	"qldiemsv/middleware"
 )
 
 func instructorsRouter(r fiber.Router) {
 	instructorsRoute := r.Group("instructors")
 
 	//[GET] /api/instructors
-	instructorsRoute.Add("GET", "", controllers.InstructorGetAll)
-	instructorsRoute.Add("GET", "department/:id", controllers.InstructorGetAllByDepartmentId)
-	instructorsRoute.Add("GET", ":id", controllers.InstructorGetById)
+	instructorsRoute.Add("GET", "", middleware.Permission(common.PermInstructorRead), controllers.InstructorGetAll)
+	instructorsRoute.Add("GET", "department/:id", middleware.Permission(common.PermInstructorRead), controllers.InstructorGetAllByDepartmentId)
+	instructorsRoute.Add("GET", ":id", middleware.Permission(common.PermInstructorRead), controllers.InstructorGetById)
 	//[POST] /api/instructors
-	instructorsRoute.Add("POST", "", controllers.InstructorCreate)
+	instructorsRoute.Add("POST", "", middleware.Permission(common.PermInstructorWrite), controllers.InstructorCreate)
+	instructorsRoute.Add("POST", "import/preview", middleware.Permission(common.PermInstructorWrite), controllers.InstructorImportPreview)
+	instructorsRoute.Add("POST", "import", middleware.Permission(common.PermInstructorWrite), controllers.InstructorImport)
 	//[PUT] /api/instructors
-	instructorsRoute.Add("PUT", ":id", controllers.InstructorUpdateById)
+	instructorsRoute.Add("PUT", ":id", middleware.Permission(common.PermInstructorWrite), controllers.InstructorUpdateById)
 	//[DELETE] /api/instructors
-	instructorsRoute.Add("DELETE", "", controllers.InstructorDeleteAll)
-	instructorsRoute.Add("DELETE", "list", controllers.InstructorDeleteByListId)
-	instructorsRoute.Add("DELETE", ":id", controllers.InstructorDeleteById)
+	instructorsRoute.Add("DELETE", "", middleware.Permission(common.PermInstructorWrite, common.PermBulkDelete), controllers.InstructorDeleteAll)
+	instructorsRoute.Add("DELETE", "list", middleware.Permission(common.PermInstructorWrite, common.PermBulkDelete), controllers.InstructorDeleteByListId)
+	instructorsRoute.Add("DELETE", ":id", middleware.Permission(common.PermInstructorWrite), controllers.InstructorDeleteById)
 
 }
@@ -0,0 +1,25 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
+	"qldiemsv/controllers"
+	"qldiemsv/middleware"
+)
+
+// jobsPublicRouter serves the downloads, the token of the link stands in for the login
+func jobsPublicRouter(r fiber.Router) {
+	jobsRoute := r.Group("jobs")
+
+	jobsRoute.Add("GET", ":id/download", controllers.ExportJobDownload)
+}
+
+func jobsRouter(r fiber.Router) {
+	jobsRoute := r.Group("jobs")
+
+	jobsRoute.Add("GET", "", middleware.Permission(common.PermGradeExport), controllers.ExportJobGetAll)
+	jobsRoute.Add("POST", "exports", middleware.Permission(common.PermGradeRead, common.PermGradeExport), controllers.ExportJobCreate)
+	jobsRoute.Add("GET", ":id", middleware.Permission(common.PermGradeExport), controllers.ExportJobGetById)
+	jobsRoute.Add("POST", ":id/cancel", middleware.Permission(common.PermGradeExport), controllers.ExportJobCancelById)
+	jobsRoute.Add("POST", ":id/link", middleware.Permission(common.PermGradeExport), controllers.ExportJobLinkById)
+}
@@ -0,0 +1,27 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
+	"qldiemsv/controllers"
+	"qldiemsv/middleware"
+)
+
+func meRouter(r fiber.Router) {
+	meRoute := r.Group("me")
+
+	instructorRoute := meRoute.Group("instructor", middleware.Permission(common.PermInstructorPortal))
+	instructorRoute.Add("GET", "", controllers.MeInstructorGet)
+	instructorRoute.Add("GET", "subjects", controllers.MeInstructorSubjectGetAll)
+	instructorRoute.Add("GET", "subjects/:id/students", controllers.MeInstructorSubjectStudentGetAll)
+	instructorRoute.Add("POST", "grades", controllers.MeInstructorGradeCreate)
+	instructorRoute.Add("PUT", "grades/:id", controllers.MeInstructorGradeUpdateById)
+
+	studentRoute := meRoute.Group("student", middleware.Permission(common.PermStudentPortal))
+	studentRoute.Add("GET", "", controllers.MeStudentGet)
+	studentRoute.Add("GET", "grades", controllers.MeStudentGradeGetAll)
+	studentRoute.Add("GET", "academic-summary", controllers.MeStudentAcademicSummaryGet)
+	studentRoute.Add("GET", "attempts", controllers.MeStudentAttemptGetAll)
+	studentRoute.Add("GET", "registrations", controllers.MeStudentRegistrationGetAll)
+	studentRoute.Add("GET", "class", controllers.MeStudentClassGet)
+}
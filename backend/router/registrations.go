@@ -2,16 +2,18 @@ package router
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
 	"qldiemsv/controllers"
+	"qldiemsv/middleware"
 )
 
 func registrationsRouter(r fiber.Router) {
 	registrationsRoute := r.Group("registrations")
 
-	registrationsRoute.Add("GET", "", controllers.RegistrationGetAll)
-	registrationsRoute.Add("GET", "department/:id", controllers.RegistrationGetAllByDepartmentId)
-	registrationsRoute.Add("GET", "student/:name", controllers.RegistrationGetAllStudentByFullName)
-	registrationsRoute.Add("POST", "", controllers.RegistrationCreate)
-	registrationsRoute.Add("PUT", ":id", controllers.RegistrationUpdateById)
-	registrationsRoute.Add("DELETE", ":id", controllers.RegistrationDeleteById)
+	registrationsRoute.Add("GET", "", middleware.Permission(common.PermRegistrationRead), controllers.RegistrationGetAll)
+	registrationsRoute.Add("GET", "department/:id", middleware.Permission(common.PermRegistrationRead), controllers.RegistrationGetAllByDepartmentId)
+	registrationsRoute.Add("GET", "student/:name", middleware.Permission(common.PermRegistrationRead), controllers.RegistrationGetAllStudentByFullName)
+	registrationsRoute.Add("POST", "", middleware.Permission(common.PermRegistrationWrite), controllers.RegistrationCreate)
+	registrationsRoute.Add("PUT", ":id", middleware.Permission(common.PermRegistrationWrite), controllers.RegistrationUpdateById)
+	registrationsRoute.Add("DELETE", ":id", middleware.Permission(common.PermRegistrationWrite), controllers.RegistrationDeleteById)
 }
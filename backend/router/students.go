@@ -2,18 +2,25 @@ package router
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
 	"qldiemsv/controllers"
+	"qldiemsv/middleware"
 )
 
 func studentsRouter(r fiber.Router) {
 	studentsRoute := r.Group("students")
 
-	studentsRoute.Add("GET", "", controllers.StudentGetAll)
-	studentsRoute.Add("GET", ":id", controllers.StudentGetById)
-	studentsRoute.Add("POST", "", controllers.StudentCreate)
-	studentsRoute.Add("PUT", ":id", controllers.StudentUpdateById)
-	studentsRoute.Add("DELETE", "", controllers.StudentDeleteAll)
-	studentsRoute.Add("DELETE", "list", controllers.StudentDeleteByListId)
-	studentsRoute.Add("DELETE", ":id", controllers.StudentDeleteById)
-	studentsRoute.Add("GET", "department/:departmentID", controllers.GetStudentsByDepartmentID)
+	studentsRoute.Add("GET", "", middleware.Permission(common.PermStudentRead), controllers.StudentGetAll)
+	studentsRoute.Add("GET", ":id", middleware.Permission(common.PermStudentRead), controllers.StudentGetById)
+	studentsRoute.Add("GET", ":id/academic-summary", middleware.Permission(common.PermStudentRead, common.PermGradeRead), controllers.StudentAcademicSummaryGetById)
+	studentsRoute.Add("GET", ":id/grades/as-of", middleware.Permission(common.PermStudentRead, common.PermGradeRead), controllers.StudentGradeAsOfGetById)
+	studentsRoute.Add("GET", ":id/attempts", middleware.Permission(common.PermStudentRead, common.PermGradeRead), controllers.StudentAttemptGetById)
+	studentsRoute.Add("POST", "", middleware.Permission(common.PermStudentWrite), controllers.StudentCreate)
+	studentsRoute.Add("POST", "import/preview", middleware.Permission(common.PermStudentWrite), controllers.StudentImportPreview)
+	studentsRoute.Add("POST", "import", middleware.Permission(common.PermStudentWrite), controllers.StudentImport)
+	studentsRoute.Add("PUT", ":id", middleware.Permission(common.PermStudentWrite), controllers.StudentUpdateById)
+	studentsRoute.Add("DELETE", "", middleware.Permission(common.PermStudentWrite, common.PermBulkDelete), controllers.StudentDeleteAll)
+	studentsRoute.Add("DELETE", "list", middleware.Permission(common.PermStudentWrite, common.PermBulkDelete), controllers.StudentDeleteByListId)
+	studentsRoute.Add("DELETE", ":id", middleware.Permission(common.PermStudentWrite), controllers.StudentDeleteById)
+	studentsRoute.Add("GET", "department/:departmentID", middleware.Permission(common.PermStudentRead), controllers.GetStudentsByDepartmentID)
 }
@@ -2,18 +2,21 @@ package router
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
 	"qldiemsv/controllers"
+	"qldiemsv/middleware"
 )
 
 func subjectsRouter(r fiber.Router) {
 	subjectsRoute := r.Group("subjects")
 
-	subjectsRoute.Add("GET", "", controllers.SubjectGetAll)
-	subjectsRoute.Add("GET", ":id", controllers.SubjectGetById)
-	subjectsRoute.Add("POST", "", controllers.SubjectCreate)
-	subjectsRoute.Add("PUT", ":id", controllers.SubjectUpdateById)
-	subjectsRoute.Add("DELETE", "", controllers.SubjectDeleteAll)
-	subjectsRoute.Add("DELETE", "list", controllers.SubjectDeleteByListId)
-	subjectsRoute.Add("DELETE", ":id", controllers.SubjectDeleteById)
-	subjectsRoute.Add("GET", "/department/:departmentID", controllers.GetSubjectsByDepartmentID)
+	subjectsRoute.Add("GET", "", middleware.Permission(common.PermSubjectRead), controllers.SubjectGetAll)
+	subjectsRoute.Add("GET", ":id", middleware.Permission(common.PermSubjectRead), controllers.SubjectGetById)
+	subjectsRoute.Add("POST", "", middleware.Permission(common.PermSubjectWrite), controllers.SubjectCreate)
+	subjectsRoute.Add("PUT", ":id", middleware.Permission(common.PermSubjectWrite), controllers.SubjectUpdateById)
+	subjectsRoute.Add("PUT", ":id/grading-scale", middleware.Permission(common.PermSubjectWrite, common.PermGradingScaleRead), controllers.SubjectUpdateGradingScaleById)
+	subjectsRoute.Add("DELETE", "", middleware.Permission(common.PermSubjectWrite, common.PermBulkDelete), controllers.SubjectDeleteAll)
+	subjectsRoute.Add("DELETE", "list", middleware.Permission(common.PermSubjectWrite, common.PermBulkDelete), controllers.SubjectDeleteByListId)
+	subjectsRoute.Add("DELETE", ":id", middleware.Permission(common.PermSubjectWrite), controllers.SubjectDeleteById)
+	subjectsRoute.Add("GET", "/department/:departmentID", middleware.Permission(common.PermSubjectRead), controllers.GetSubjectsByDepartmentID)
 }
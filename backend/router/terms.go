@@ -0,0 +1,19 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
+	"qldiemsv/controllers"
+	"qldiemsv/middleware"
+)
+
+func termsRouter(r fiber.Router) {
+	termsRoute := r.Group("terms")
+
+	termsRoute.Add("GET", "", middleware.Permission(common.PermTermRead), controllers.TermGetAll)
+	termsRoute.Add("GET", "current", middleware.Permission(common.PermTermRead), controllers.TermGetCurrent)
+	termsRoute.Add("GET", ":id", middleware.Permission(common.PermTermRead), controllers.TermGetById)
+	termsRoute.Add("POST", "", middleware.Permission(common.PermTermWrite), controllers.TermCreate)
+	termsRoute.Add("PUT", ":id", middleware.Permission(common.PermTermWrite), controllers.TermUpdateById)
+	termsRoute.Add("DELETE", ":id", middleware.Permission(common.PermTermWrite), controllers.TermDeleteById)
+}
@@ -2,11 +2,28 @@ package router
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"qldiemsv/common"
 	"qldiemsv/controllers"
+	"qldiemsv/middleware"
 )
 
 func usersRouter(r fiber.Router) {
 	usersRoute := r.Group("users")
 
 	usersRoute.Add("GET", "me", controllers.UserMe)
+	usersRoute.Add("GET", "", middleware.Permission(common.PermUserManage), controllers.UserGetAll)
+	usersRoute.Add("POST", "", middleware.Permission(common.PermUserManage), controllers.UserCreate)
+	usersRoute.Add("GET", "invitations", middleware.Permission(common.PermUserManage), controllers.InvitationGetAll)
+	usersRoute.Add("POST", "invitations", middleware.Permission(common.PermUserManage), controllers.InvitationCreate)
+	usersRoute.Add("DELETE", "invitations/:id", middleware.Permission(common.PermUserManage), controllers.InvitationDeleteById)
+	usersRoute.Add("GET", "lockouts", middleware.Permission(common.PermUserManage), controllers.UserLockoutGetAll)
+	usersRoute.Add("DELETE", "lockouts/:id", middleware.Permission(common.PermUserManage), controllers.UserLockoutDeleteById)
+	usersRoute.Add("GET", ":id", middleware.Permission(common.PermUserManage), controllers.UserGetById)
+	usersRoute.Add("DELETE", ":id", middleware.Permission(common.PermUserManage), controllers.UserDisableById)
+	usersRoute.Add("POST", ":id/restore", middleware.Permission(common.PermUserManage), controllers.UserRestoreById)
+	usersRoute.Add("PUT", ":id/role", middleware.Permission(common.PermUserManage), controllers.UserUpdateRoleById)
+	usersRoute.Add("PUT", ":id/departments", middleware.Permission(common.PermUserManage), controllers.UserUpdateDepartmentsById)
+	usersRoute.Add("PUT", ":id/instructor", middleware.Permission(common.PermUserManage), controllers.UserUpdateInstructorById)
+	usersRoute.Add("PUT", ":id/student", middleware.Permission(common.PermUserManage), controllers.UserUpdateStudentById)
+	usersRoute.Add("PUT", ":id/2fa", middleware.Permission(common.PermUserManage), controllers.UserUpdateTwoFactorById)
 }